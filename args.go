@@ -0,0 +1,113 @@
+package bytefmt
+
+// arg returns the argument at index i, aborting formatting with an
+// *ArgError if there is none.
+func (d *Dumper) arg(a []interface{}, i int) interface{} {
+	if i < 0 || i >= len(a) {
+		d.badArg(i)
+	}
+	return a[i]
+}
+
+// badArg aborts formatting with an *ArgError for argument i.
+func (d *Dumper) badArg(i int) {
+	panic(&ArgError{Verb: d.verb, Index: i})
+}
+
+// argMap returns argument i as a map[int64]string.
+func (d *Dumper) argMap(a []interface{}, i int) map[int64]string {
+	m, ok := d.arg(a, i).(map[int64]string)
+	if !ok {
+		d.badArg(i)
+	}
+	return m
+}
+
+// argString returns argument i as a string.
+func (d *Dumper) argString(a []interface{}, i int) string {
+	s, ok := d.arg(a, i).(string)
+	if !ok {
+		d.badArg(i)
+	}
+	return s
+}
+
+// argFloat returns argument i as a float64.
+func (d *Dumper) argFloat(a []interface{}, i int) float64 {
+	f, ok := d.arg(a, i).(float64)
+	if !ok {
+		d.badArg(i)
+	}
+	return f
+}
+
+// argInt returns argument i as an int.
+func (d *Dumper) argInt(a []interface{}, i int) int {
+	x, ok := d.arg(a, i).(int)
+	if !ok {
+		d.badArg(i)
+	}
+	return x
+}
+
+// argInt64 returns argument i as an int64.
+func (d *Dumper) argInt64(a []interface{}, i int) int64 {
+	x, ok := d.arg(a, i).(int64)
+	if !ok {
+		d.badArg(i)
+	}
+	return x
+}
+
+// argBitFields returns argument i as a []BitField.
+func (d *Dumper) argBitFields(a []interface{}, i int) []BitField {
+	f, ok := d.arg(a, i).([]BitField)
+	if !ok {
+		d.badArg(i)
+	}
+	return f
+}
+
+// argTable returns argument i as a [][2]float64.
+func (d *Dumper) argTable(a []interface{}, i int) [][2]float64 {
+	t, ok := d.arg(a, i).([][2]float64)
+	if !ok {
+		d.badArg(i)
+	}
+	return t
+}
+
+// argWidth sets the width of a * or {name} format from its argument.
+// A * takes the next int argument not yet used by a *, skipping other
+// arguments, a name is looked up in the first map[string]int argument.
+func (d *Dumper) argWidth(a []interface{}) {
+	i := d.argi
+	if d.widthName == "" {
+		for i < len(a) {
+			if _, ok := a[i].(int); ok {
+				break
+			}
+			i++
+		}
+		d.width = d.argInt(a, i)
+		d.argi = i + 1
+	} else {
+		i = len(a)
+		var m map[string]int
+		for j, x := range a {
+			var ok bool
+			if m, ok = x.(map[string]int); ok {
+				i = j
+				break
+			}
+		}
+		w, ok := m[d.widthName]
+		if !ok {
+			d.badArg(i)
+		}
+		d.width = w
+	}
+	if d.width < 0 || tooLarge(d.width) {
+		d.badArg(i)
+	}
+}
@@ -0,0 +1,99 @@
+package bytefmt
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBadArg(t *testing.T) {
+	var tests = []struct {
+		fmt    string
+		expect string
+	}{
+		{"%1.0e", "one"},
+		{"%1.1e", "%%BADARG%1"},
+		{"%1.5e", "%%BADARG%5"},
+		{"%1.2b %1d", "%%BADARG%2"},
+		{"%1.1t", "%%BADARG%1"},
+		{"%1.0i", "%%BADARG%0"},
+		{"%1.1i", "0.5"},
+		{"%1d %1.3(sentinel)", "1 %%BADARG%3"},
+	}
+	for _, tt := range tests {
+		res := Sprintf([]byte{1, 2}, tt.fmt, map[int64]string{1: "one"}, 0.5, 2)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+	var b bytes.Buffer
+	_, err := Fprintf(&b, []byte{1}, "%1.0e")
+	if e, ok := err.(*ArgError); !ok || e.Verb != "%1.0e" || e.Index != 0 {
+		t.Logf("unexpected error %v", err)
+		t.Fail()
+	}
+}
+
+func TestArgType(t *testing.T) {
+	var tests = []struct {
+		fmt    string
+		expect string
+	}{
+		{"%1.0e", "%%BADARG%0"},
+		{"%1.1e", "%%BADARG%1"},
+		{"%1.0i", "%%BADARG%0"},
+		{"%.0k", "%%BADARG%0"},
+		{"%1.0(percent)", "%%BADARG%0"},
+		{"%1.1(bar)", "%%BADARG%2"},
+		{"%1.0(fields)", "%%BADARG%0"},
+		{"%.0(interp)", "%%BADARG%0"},
+		{"%.0(taitime)", "%%BADARG%0"},
+		{"%.3(taitime)", "%%BADARG%3"},
+		{"%*s", "%%BADARG%2"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(make([]byte, 8), tt.fmt, true, 1.0)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+		if _, err := SprintfStrict(make([]byte, 8), tt.fmt, true, 1.0); err == nil {
+			t.Logf("format %q: expected an error", tt.fmt)
+			t.Fail()
+		}
+	}
+}
+
+func TestNamedWidth(t *testing.T) {
+	sizes := map[string]int{"headerLen": 2, "body": 3, "neg": -1}
+	var tests = []struct {
+		fmt    string
+		expect string
+	}{
+		{"%{headerLen}s|%{body}s", "ab|cde"},
+		{"%{headerLen}v*2", "24930, 25444"},
+		{"%1.0e %{body}s", "one bcd"},
+		{"%{nosuch}s", "%%BADARG%1"},
+		{"%{neg}s", "%%BADARG%1"},
+		{"%{headerLen", "%%UNKOWN%{headerLen"},
+	}
+	for _, tt := range tests {
+		res := Sprintf([]byte("abcdef"), tt.fmt, map[int64]string{0x61: "one"}, sizes, 1)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+	if res := Sprintf([]byte("ab"), "%{headerLen}s"); res != "%%BADARG%0" {
+		t.Logf("no map: unexpected %q", res)
+		t.Fail()
+	}
+	if err := Validate("%{body}s"); err != nil {
+		t.Logf("validate: unexpected %v", err)
+		t.Fail()
+	}
+	if e, ok := Validate("%{body").(*FormatError); !ok || e.Problem != "missing }" {
+		t.Logf("validate missing }: unexpected %v", e)
+		t.Fail()
+	}
+}
@@ -0,0 +1,135 @@
+package bytefmt
+
+import (
+	"encoding/csv"
+	"strconv"
+)
+
+// fmtEnums prints prec enumerated values of width (default 1) bytes
+// each, looked up in the map[int64]string argument given by the
+// parameter (default 0).
+func (d *Dumper) fmtEnums(a []interface{}) {
+	if !d.widthValid {
+		d.width = 1
+	}
+	m := d.argMap(a, d.paramInt(0, 0))
+	if !d.enumWidthOK(m) {
+		return
+	}
+	for n := 0; n < d.prec; n++ {
+		if n > 0 {
+			d.buf.WriteString(d.sep())
+		}
+		x := d.fetchInt()
+		if s, ok := m[x]; ok {
+			d.buf.WriteString(s)
+		} else if d.StrictEnum {
+			d.badValue(strconv.FormatInt(x, 10))
+		} else {
+			d.buf.WriteString(strconv.FormatInt(x, 10))
+		}
+	}
+}
+
+// fmtDeltas prints a delta encoded list stored as a count, a base value
+// and count-1 signed deltas, all of width (default 2) bytes.
+func (d *Dumper) fmtDeltas(a []interface{}) {
+	if !d.widthValid {
+		d.width = 2
+	}
+	n := d.fetchInt()
+	if n == 0 {
+		return
+	}
+	x := d.fetchInt()
+	d.buf.WriteString(strconv.FormatInt(x, 10))
+	for ; n > 1; n-- {
+		x += signExtend(d.fetchInt(), d.width)
+		d.buf.WriteString(d.sep())
+		d.buf.WriteString(strconv.FormatInt(x, 10))
+	}
+}
+
+// fmtAgg prints an aggregate of a count prefixed array of width (default
+// 2) byte ints, selected by the parameter: sum (default), avg, min or
+// max. The + flag makes the elements signed.
+func (d *Dumper) fmtAgg(a []interface{}) {
+	if !d.widthValid {
+		d.width = 2
+	}
+	agg := "sum"
+	if len(d.params) > 0 {
+		agg = d.params[0]
+	}
+	n := d.fetchInt()
+	var sum, min, max int64
+	for i := int64(0); i < n; i++ {
+		x := d.fetchSigned()
+		if i == 0 || x < min {
+			min = x
+		}
+		if i == 0 || x > max {
+			max = x
+		}
+		sum += x
+	}
+	switch {
+	case agg == "sum":
+		d.buf.WriteString(strconv.FormatInt(sum, 10))
+	case agg != "avg" && agg != "min" && agg != "max":
+		d.badValue(agg)
+	case n == 0:
+	case agg == "avg":
+		d.buf.WriteString(strconv.FormatFloat(float64(sum)/float64(n), 'g', -1, 64))
+	case agg == "min":
+		d.buf.WriteString(strconv.FormatInt(min, 10))
+	case agg == "max":
+		d.buf.WriteString(strconv.FormatInt(max, 10))
+	}
+}
+
+// fmtCSV prints prec elements of width (default 2) bytes each as a CSV
+// row, reading a 1 byte element count first if no precision is given.
+// The elements are ints, signed with the + flag, or strings with the #
+// flag.
+func (d *Dumper) fmtCSV(a []interface{}) {
+	if !d.widthValid {
+		d.width = 2
+	}
+	n := d.prec
+	if !d.precValid {
+		n = int(d.fetchBytes(1)[0])
+	}
+	row := make([]string, n)
+	for i := range row {
+		switch {
+		case d.altFlag:
+			row[i] = string(d.fetchBytes(d.width))
+		default:
+			row[i] = strconv.FormatInt(d.fetchSigned(), 10)
+		}
+	}
+	w := csv.NewWriter(&d.buf)
+	w.Write(row)
+	w.Flush()
+}
+
+// fmtSentinel prints ints of width (default 2) bytes up to the int64
+// sentinel argument selected by prec, which is consumed but not printed,
+// or up to the end of the input.
+func (d *Dumper) fmtSentinel(a []interface{}) {
+	if !d.widthValid {
+		d.width = 2
+	}
+	sentinel := d.argInt64(a, d.prec)
+	for n := 0; d.width > 0 && d.available(d.width); n++ {
+		x := d.fetchInt()
+		if x == sentinel {
+			break
+		}
+		if n > 0 {
+			d.buf.WriteString(d.sep())
+		}
+		d.buf.WriteString(strconv.FormatInt(x, 10))
+	}
+}
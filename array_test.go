@@ -0,0 +1,120 @@
+package bytefmt
+
+import (
+	"testing"
+)
+
+func TestEnums(t *testing.T) {
+	var kinds = map[int64]string{
+		0x0101: "Header",
+		0x0202: "Body",
+	}
+	buf := []byte{0x02, 0x02, 0x01, 0x01, 0x00, 0x07}
+	res := Sprintf(buf, "%2.3(enums)", kinds)
+	expected := "Body, Header, 7"
+	if res != expected {
+		t.Logf("enums expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf(buf[2:], "%-2.2(enums:1)", nil, kinds)
+	expected = "Header, 1792"
+	if res != expected {
+		t.Logf("enums expected %q, res %q", expected, res)
+		t.Fail()
+	}
+}
+
+func TestDeltas(t *testing.T) {
+	buf := []byte{0x00, 0x04, 0x03, 0xe8, 0x00, 0x01, 0x00, 0x05, 0xff, 0xfe}
+	res := Sprintf(buf, "%(deltas)")
+	expected := "1000, 1001, 1006, 1004"
+	if res != expected {
+		t.Logf("deltas expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf([]byte{0x02, 0x10, 0x0f}, "%1(deltas)")
+	expected = "16, 31"
+	if res != expected {
+		t.Logf("deltas expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf([]byte{0x00}, "%1(deltas)")
+	expected = ""
+	if res != expected {
+		t.Logf("deltas expected %q, res %q", expected, res)
+		t.Fail()
+	}
+}
+
+func TestAgg(t *testing.T) {
+	buf := []byte{0x00, 0x04, 0x00, 0x03, 0xff, 0xfe, 0x00, 0x0a, 0x00, 0x02}
+	var tests = []struct {
+		fmt    string
+		expect string
+	}{
+		{"%(agg)", "65549"},
+		{"%+(agg:sum)", "13"},
+		{"%+(agg:avg)", "3.25"},
+		{"%+(agg:min)", "-2"},
+		{"%+(agg:max)", "10"},
+		{"%(agg:max)", "65534"},
+		{"%(agg:median)", "%%BADVALUE%median"},
+		{"%1(agg:min)", ""},
+	}
+	for _, tt := range tests {
+		res := Sprintf(buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestCSV(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x00, 0x01, 0x00, 0x02, 0xff, 0xff}, "%2.3(csv)", "1,2,65535\n"},
+		{[]byte{0x03, 0x01, 0x02, 0xff}, "%+1(csv)", "1,2,-1\n"},
+		{[]byte("ab,c\"de"), "%#3.2(csv)", "\"ab,\",\"c\"\"d\"\n"},
+		{[]byte{0x00}, "%(csv)", "\n"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestSentinel(t *testing.T) {
+	var end int64 = 0xffff
+	buf := []byte{0x00, 0x01, 0x00, 0x02, 0xff, 0xff, 0x42}
+	res := Sprintf(buf, "%.0(sentinel) %1x", end)
+	expected := "1, 2 42"
+	if res != expected {
+		t.Logf("sentinel expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf(buf[:4], "%.0(sentinel)", end)
+	expected = "1, 2"
+	if res != expected {
+		t.Logf("sentinel expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf(buf[:5], "%.0(sentinel)", end)
+	expected = "1, 2"
+	if res != expected {
+		t.Logf("sentinel expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf(buf, "%0.0(sentinel)%1x", end)
+	expected = "0"
+	if res != expected {
+		t.Logf("sentinel expected %q, res %q", expected, res)
+		t.Fail()
+	}
+}
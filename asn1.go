@@ -0,0 +1,57 @@
+package bytefmt
+
+import (
+	"strconv"
+)
+
+// fmtOID prints an ASN.1 object identifier of width (default the rest)
+// bytes in dotted form, e.g. 1.2.840.113549. Malformed encodings are
+// flagged with BadValue.
+func (d *Dumper) fmtOID(a []interface{}) {
+	d.restWidth()
+	b := d.fetchBytes(d.width)
+	var s []byte
+	for i := 0; i < len(b); {
+		arc, n, ok := base128(b[i:])
+		if !ok {
+			d.badValue("")
+			return
+		}
+		if i == 0 {
+			first := arc / 40
+			if first > 2 {
+				first = 2
+			}
+			s = strconv.AppendUint(s, first, 10)
+			arc -= 40 * first
+		}
+		s = append(s, '.')
+		s = strconv.AppendUint(s, arc, 10)
+		i += n
+	}
+	if len(s) == 0 {
+		d.badValue("")
+		return
+	}
+	d.buf.Write(s)
+}
+
+// base128 decodes the big endian base 128 number at the start of b, as
+// used for the arcs of an OID. n is the number of bytes it takes, ok is
+// false if it is cut short, not minimally encoded or overflows 64 bits.
+func base128(b []byte) (x uint64, n int, ok bool) {
+	if len(b) > 0 && b[0] == 0x80 {
+		return 0, 0, false
+	}
+	for n < len(b) {
+		if x>>57 != 0 {
+			return 0, 0, false
+		}
+		x = x<<7 | uint64(b[n]&0x7f)
+		n++
+		if b[n-1] < 0x80 {
+			return x, n, true
+		}
+	}
+	return 0, 0, false
+}
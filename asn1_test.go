@@ -0,0 +1,31 @@
+package bytefmt
+
+import (
+	"testing"
+)
+
+func TestOID(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x2a, 0x86, 0x48, 0x86, 0xf7, 0x0d}, "%(oid)", "1.2.840.113549"},
+		{[]byte{0x55, 0x04, 0x03, 0x05}, "%3(oid) %1d", "2.5.4.3 5"},
+		{[]byte{0x06}, "%(oid)", "0.6"},
+		{[]byte{0x88, 0x37, 0x01}, "%(oid)", "2.999.1"},
+		{[]byte{0x2b, 0x06, 0x01, 0x04, 0x01, 0x82, 0x37}, "%(oid)", "1.3.6.1.4.1.311"},
+		{[]byte{0x2a, 0x86}, "%(oid)", "%%BADVALUE%"},
+		{[]byte{0x2a, 0x80, 0x01}, "%(oid)", "%%BADVALUE%"},
+		{[]byte{0x2a, 0x82, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x00}, "%(oid)", "%%BADVALUE%"},
+		{[]byte{}, "%(oid)", "%%BADVALUE%"},
+		{[]byte{0x2a}, "%2(oid)", "%%EOF%"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
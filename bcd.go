@@ -0,0 +1,140 @@
+package bytefmt
+
+import (
+	"encoding/hex"
+)
+
+// bcdDigits appends the two decimal digits of the packed BCD byte b to
+// dst. ok is false if either nibble is not a decimal digit.
+func bcdDigits(dst []byte, b byte) (res []byte, ok bool) {
+	hi, lo := b>>4, b&0xf
+	if hi > 9 || lo > 9 {
+		return dst, false
+	}
+	return append(dst, '0'+hi, '0'+lo), true
+}
+
+// fmtBCD prints the digits of width (default 4) packed BCD bytes, in
+// reverse byte order with the - flag. Filler nibbles 0xf are skipped and
+// other invalid ones printed as '?'. With the # flag the low nibble of
+// each byte comes first, with the + flag the bytes are a signed packed
+// decimal.
+func (d *Dumper) fmtBCD() {
+	if !d.widthValid {
+		d.width = 4
+	}
+	b := append([]byte(nil), d.fetchBytes(d.width)...)
+	if d.intel {
+		for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+			b[i], b[j] = b[j], b[i]
+		}
+	}
+	if d.signed {
+		digits, neg, ok := packedDecimal(b)
+		if !ok {
+			d.badValue(hex.EncodeToString(b))
+			return
+		}
+		if neg {
+			d.buf.WriteRune('-')
+		}
+		d.buf.Write(digits)
+		return
+	}
+	for _, c := range b {
+		n := []byte{c >> 4, c & 0xf}
+		if d.altFlag {
+			n[0], n[1] = n[1], n[0]
+		}
+		for _, x := range n {
+			switch {
+			case x <= 9:
+				d.buf.WriteByte('0' + x)
+			case x != 0xf:
+				d.buf.WriteRune('?')
+			}
+		}
+	}
+}
+
+// fmtBCDTime prints a six byte packed BCD YY MM DD HH MM SS date time
+// as ISO 8601. The year is taken to be 20YY, or 19YY with the # flag.
+func (d *Dumper) fmtBCDTime(a []interface{}) {
+	b := d.fetchBytes(6)
+	res := []byte("20")
+	if d.altFlag {
+		res = []byte("19")
+	}
+	ok := true
+	for i, sep := range []byte("--T::") {
+		if res, ok = bcdDigits(res, b[i]); !ok {
+			break
+		}
+		res = append(res, sep)
+	}
+	if ok {
+		res, ok = bcdDigits(res, b[5])
+	}
+	if !ok {
+		d.badValue(hex.EncodeToString(b))
+		return
+	}
+	d.buf.Write(res)
+}
+
+// packedDecimal decodes the COBOL style signed packed decimal b, whose
+// last nibble is the sign: 0xb or 0xd for negative, 0xa, 0xc, 0xe or
+// 0xf for positive.
+func packedDecimal(b []byte) (digits []byte, neg bool, ok bool) {
+	for i, c := range b {
+		hi, lo := c>>4, c&0xf
+		if hi > 9 {
+			return nil, false, false
+		}
+		digits = append(digits, '0'+hi)
+		if i < len(b)-1 {
+			if lo > 9 {
+				return nil, false, false
+			}
+			digits = append(digits, '0'+lo)
+			continue
+		}
+		switch lo {
+		case 0xb, 0xd:
+			neg = true
+		case 0xa, 0xc, 0xe, 0xf:
+		default:
+			return nil, false, false
+		}
+	}
+	return digits, neg, len(b) > 0
+}
+
+// fmtBCDPercent prints a signed packed decimal percentage of width
+// (default 2) bytes with prec implied decimal places.
+func (d *Dumper) fmtBCDPercent(a []interface{}) {
+	if !d.widthValid {
+		d.width = 2
+	}
+	b := d.fetchBytes(d.width)
+	digits, neg, ok := packedDecimal(b)
+	if !ok {
+		d.badValue(hex.EncodeToString(b))
+		return
+	}
+	for len(digits) <= d.prec {
+		digits = append([]byte{'0'}, digits...)
+	}
+	for len(digits)-d.prec > 1 && digits[0] == '0' {
+		digits = digits[1:]
+	}
+	if neg {
+		d.buf.WriteRune('-')
+	}
+	d.buf.Write(digits[:len(digits)-d.prec])
+	if d.prec > 0 {
+		d.buf.WriteRune('.')
+		d.buf.Write(digits[len(digits)-d.prec:])
+	}
+	d.buf.WriteRune('%')
+}
@@ -0,0 +1,72 @@
+package bytefmt
+
+import (
+	"testing"
+)
+
+func TestBCDTime(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x24, 0x01, 0x31, 0x12, 0x30, 0x59}, "%(bcdtime)", "2024-01-31T12:30:59"},
+		{[]byte{0x00, 0x12, 0x01, 0x00, 0x00, 0x00}, "%(bcdtime)", "2000-12-01T00:00:00"},
+		{[]byte{0x00, 0x12, 0x01, 0x00, 0x00, 0x00}, "%#(bcdtime)", "1900-12-01T00:00:00"},
+		{[]byte{0x99, 0x1a, 0x01, 0x00, 0x00, 0x00}, "%(bcdtime)", "%%BADVALUE%991a01000000"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestBCDPercent(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x01, 0x23, 0x4c}, "%3.2(bcdpct)", "12.34%"},
+		{[]byte{0x01, 0x23, 0x4d}, "%3.2(bcdpct)", "-12.34%"},
+		{[]byte{0x05, 0x0f}, "%.3(bcdpct)", "0.050%"},
+		{[]byte{0x99, 0x9c}, "%(bcdpct)", "999%"},
+		{[]byte{0x01, 0x23}, "%(bcdpct)", "%%BADVALUE%0123"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestBCD(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x12, 0x34, 0x56, 0x78}, "%N", "12345678"},
+		{[]byte{0x12, 0x34, 0x56, 0x78}, "%-4N", "78563412"},
+		{[]byte{0x00, 0x42}, "%2N", "0042"},
+		{[]byte{0x12, 0x3f}, "%2N", "123"},
+		{[]byte{0x1a, 0x23}, "%2N", "1?23"},
+		{[]byte{0x21, 0x43, 0xf5}, "%#3N", "12345"},
+		{[]byte{0x12, 0x34, 0x5d}, "%+3N", "-12345"},
+		{[]byte{0x12, 0x34, 0x5c}, "%+3N", "12345"},
+		{[]byte{0x5c, 0x34, 0x12}, "%+-3N", "12345"},
+		{[]byte{0x12, 0x34}, "%+2N", "%%BADVALUE%1234"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
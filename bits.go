@@ -0,0 +1,235 @@
+package bytefmt
+
+import (
+	"math/bits"
+	"strconv"
+)
+
+// Verbs working on bits rather than bytes share a bit cursor: d.bit
+// counts the bits of d.input[d.ii] already consumed, most significant
+// bit first, or least significant first if the Dumper has LSBFirst set.
+// Byte oriented verbs skip the rest of a partially consumed byte.
+
+// alignByte moves the read position to the start of the next byte if
+// bits of the current byte have been consumed.
+func (d *Dumper) alignByte() {
+	if d.bit != 0 {
+		d.ii++
+		d.bit = 0
+	}
+}
+
+// fetchBits consumes the next n (at most 64) bits of the input. The
+// first bit is the most significant one of the result, or with LSBFirst
+// the least significant one.
+func (d *Dumper) fetchBits(n int) uint64 {
+	var val uint64
+	for i := 0; i < n; i++ {
+		if !d.fill(d.ii + 1) {
+			panic(&TruncatedError{Verb: d.verb, Offset: d.ii})
+		}
+		if d.LSBFirst {
+			val |= uint64(d.input[d.ii]>>d.bit&1) << uint(i)
+		} else {
+			val = val<<1 | uint64(d.input[d.ii]>>(7-d.bit)&1)
+		}
+		d.bit++
+		if d.bit == 8 {
+			d.ii++
+			d.bit = 0
+		}
+	}
+	return val
+}
+
+// fmtBits prints the next width (default 1, at most 64) bits as an
+// unsigned decimal.
+func (d *Dumper) fmtBits(a []interface{}) {
+	if !d.widthValid {
+		d.width = 1
+	}
+	if d.width > 64 {
+		d.badWidth(d.width)
+		return
+	}
+	d.buf.WriteString(strconv.FormatUint(d.fetchBits(d.width), 10))
+}
+
+// fmtBitInt prints the next prec bits as a decimal, sign extended with
+// the + flag.
+func (d *Dumper) fmtBitInt() {
+	if d.prec > 64 {
+		d.badWidth(d.prec)
+		return
+	}
+	x := d.fetchBits(d.prec)
+	if d.signed && d.prec > 0 {
+		shift := uint(64 - d.prec)
+		d.writeDecimal(strconv.FormatInt(int64(x<<shift)>>shift, 10))
+		return
+	}
+	d.writeDecimal(strconv.FormatUint(x, 10))
+}
+
+// fmtRice prints a Golomb-Rice coded integer with parameter prec. The
+// quotient is coded in unary as one bits terminated by a zero bit, or
+// with the # flag as zero bits terminated by a one bit. A prec over 64
+// is flagged with BadWidth.
+func (d *Dumper) fmtRice(a []interface{}) {
+	if d.prec > 64 {
+		d.badWidth(d.prec)
+		return
+	}
+	var q uint64
+	for (d.fetchBits(1) == 1) != d.altFlag {
+		q++
+	}
+	x := q<<uint(d.prec) | d.fetchBits(d.prec)
+	d.buf.WriteString(strconv.FormatUint(x, 10))
+}
+
+// fmtPresent prints a presence bitmap of width (default 8, at most 64)
+// bits, most significant first, followed by one value for each set bit,
+// decoded with the sub-format argument selected by prec.
+func (d *Dumper) fmtPresent(a []interface{}) {
+	if !d.widthValid {
+		d.width = 8
+	}
+	if d.width > 64 {
+		d.badWidth(d.width)
+		return
+	}
+	n := d.width
+	m := d.fetchBits(n)
+	d.alignByte()
+	sub := d.argString(a, d.prec)
+	var needSep = false
+	for i := 0; i < n; i++ {
+		if m&(1<<uint(n-1-i)) == 0 {
+			continue
+		}
+		if needSep {
+			d.buf.WriteString(d.sep())
+		}
+		d.buf.WriteString("field[" + strconv.Itoa(i) + "]=")
+		d.doDump(sub, a)
+		needSep = true
+	}
+}
+
+// fmtGrid draws width (default 1) bytes as rows of prec (default 8) bits,
+// most significant first, using '#' for set and '.' for clear bits.
+func (d *Dumper) fmtGrid(a []interface{}) {
+	if !d.widthValid {
+		d.width = 1
+	}
+	if !d.precValid || d.prec == 0 {
+		d.prec = 8
+	}
+	b := d.fetchBytes(d.width)
+	var row []byte
+	for i := 0; i < 8*len(b); i++ {
+		if b[i/8]&(0x80>>uint(i%8)) != 0 {
+			row = append(row, '#')
+		} else {
+			row = append(row, '.')
+		}
+		if (i+1)%d.prec == 0 || i == 8*len(b)-1 {
+			d.writeLines(string(append(row, '\n')))
+			row = row[:0]
+		}
+	}
+}
+
+// fmtEnumGroups prints width (default 1) bytes as consecutive groups of
+// prec (default 2) bits, most significant first, each looked up in the
+// map[int64]string argument given by the parameter (default 0).
+func (d *Dumper) fmtEnumGroups(a []interface{}) {
+	if !d.widthValid {
+		d.width = 1
+	}
+	if !d.precValid || d.prec == 0 {
+		d.prec = 2
+	}
+	m := d.argMap(a, d.paramInt(0, 0))
+	d.alignByte()
+	for n := 8 * d.width; n > 0; n -= d.prec {
+		if n < 8*d.width {
+			d.buf.WriteString(d.sep())
+		}
+		bits := d.prec
+		if bits > n {
+			bits = n
+		}
+		x := int64(d.fetchBits(bits))
+		if s, ok := m[x]; ok {
+			d.buf.WriteString(s)
+		} else {
+			d.buf.WriteString(strconv.FormatInt(x, 10))
+		}
+	}
+}
+
+// BitField describes a subfield of a word decoded by %(fields). A field
+// with Enum set is printed as the label of its value, one with Flags set
+// as the names of its set bits like %b, any other in decimal.
+type BitField struct {
+	Name  string
+	Bits  int
+	Enum  map[int64]string
+	Flags map[int64]string
+}
+
+// fmtFields prints a width (default 2) byte word split into the
+// subfields of the []BitField argument selected by prec, starting at the
+// most significant bit, as name=value pairs. Fields of a negative or
+// over 64 bit size, or more bits in all than the word has, are flagged
+// with BadWidth.
+func (d *Dumper) fmtFields(a []interface{}) {
+	if !d.widthValid {
+		d.width = 2
+	}
+	x := d.fetchInt()
+	fields := d.argBitFields(a, d.prec)
+	shift := 8 * d.width
+	for _, f := range fields {
+		if f.Bits < 0 || f.Bits > 64 || f.Bits > shift {
+			d.badWidth(d.width)
+			return
+		}
+		shift -= f.Bits
+	}
+	shift = 8 * d.width
+	for i, f := range fields {
+		if i > 0 {
+			d.buf.WriteRune(' ')
+		}
+		shift -= f.Bits
+		v := x >> uint(shift) & (1<<uint(f.Bits) - 1)
+		d.buf.WriteString(f.Name + "=")
+		switch {
+		case f.Enum != nil:
+			if s, ok := f.Enum[v]; ok {
+				d.buf.WriteString(s)
+			} else {
+				d.buf.WriteString(strconv.FormatInt(v, 10))
+			}
+		case f.Flags != nil:
+			d.writeFlags(v, f.Flags)
+		default:
+			d.buf.WriteString(strconv.FormatInt(v, 10))
+		}
+	}
+}
+
+// fmtPopCount prints the number of set bits of width (default 1) bytes.
+func (d *Dumper) fmtPopCount(a []interface{}) {
+	if !d.widthValid {
+		d.width = 1
+	}
+	n := 0
+	for _, b := range d.fetchBytes(d.width) {
+		n += bits.OnesCount8(b)
+	}
+	d.buf.WriteString(strconv.Itoa(n))
+}
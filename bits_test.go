@@ -0,0 +1,215 @@
+package bytefmt
+
+import (
+	"testing"
+)
+
+func TestBits(t *testing.T) {
+	res := Sprintf([]byte{0xb5, 0x42}, "%3(bits) %(bits) %(bits) %1x")
+	expected := "5 1 0 42"
+	if res != expected {
+		t.Logf("bits expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf([]byte{0xb5, 0x42}, "%4(bits) %s")
+	expected = "11 B"
+	if res != expected {
+		t.Logf("bits expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf([]byte{0xb5, 0x42}, "%100(bits) %1x")
+	expected = BadWidth + "100 b5"
+	if res != expected {
+		t.Logf("bits expected %q, res %q", expected, res)
+		t.Fail()
+	}
+}
+
+func TestRice(t *testing.T) {
+	// 110 01, 0 10, 111110 11 with k = 2
+	res := Sprintf([]byte{0xca, 0xfb}, "%.2(rice) %.2(rice) %.2(rice)")
+	expected := "9 2 23"
+	if res != expected {
+		t.Logf("rice expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	// 001 1, 1 0 with k = 1
+	res = Sprintf([]byte{0x38}, "%#.1(rice) %#.1(rice)")
+	expected = "5 0"
+	if res != expected {
+		t.Logf("rice expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf([]byte{0x38}, "%.65(rice) %1x")
+	expected = BadWidth + "65 38"
+	if res != expected {
+		t.Logf("rice expected %q, res %q", expected, res)
+		t.Fail()
+	}
+}
+
+func TestPresent(t *testing.T) {
+	res := Sprintf([]byte{0x50, 0x00, 0x0c, 0x01, 0x02}, "%4.0(present)", "%2d")
+	expected := "field[1]=12, field[3]=258"
+	if res != expected {
+		t.Logf("present expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf([]byte{0x50}, "%80.0(present) %1x", "%2d")
+	expected = BadWidth + "80 50"
+	if res != expected {
+		t.Logf("present expected %q, res %q", expected, res)
+		t.Fail()
+	}
+}
+
+func TestGrid(t *testing.T) {
+	res := Sprintf([]byte{0x3c, 0x81}, "%2(grid)")
+	expected := "..####..\n#......#\n"
+	if res != expected {
+		t.Logf("grid expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf([]byte{0x96}, "%.4(grid)")
+	expected = "#..#\n.##.\n"
+	if res != expected {
+		t.Logf("grid expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf([]byte{0xff, 0x80}, "%2.6(grid)")
+	expected = "######\n###...\n....\n"
+	if res != expected {
+		t.Logf("grid expected %q, res %q", expected, res)
+		t.Fail()
+	}
+}
+
+func TestEnumGroups(t *testing.T) {
+	var levels = map[int64]string{
+		0: "off",
+		1: "low",
+		2: "mid",
+		3: "high",
+	}
+	res := Sprintf([]byte{0x1b}, "%(enumgroups)", levels)
+	expected := "off, low, mid, high"
+	if res != expected {
+		t.Logf("enumgroups expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf([]byte{0xc8, 0x40, 0x40}, "%2.3(enumgroups:1) %1x", nil, levels)
+	expected = "6, mid, off, 4, off, off 40"
+	if res != expected {
+		t.Logf("enumgroups expected %q, res %q", expected, res)
+		t.Fail()
+	}
+}
+
+func TestFields(t *testing.T) {
+	var status = []BitField{
+		{Name: "mode", Bits: 3, Enum: map[int64]string{0: "Off", 1: "Idle", 2: "Run"}},
+		{Name: "count", Bits: 12},
+		{Name: "flags", Bits: 1, Flags: map[int64]string{1: "ready"}},
+	}
+	// 010 000000000101 1
+	res := Sprintf([]byte{0x40, 0x0b}, "%.0(fields)", status)
+	expected := "mode=Run count=5 flags=(ready)"
+	if res != expected {
+		t.Logf("fields expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	// 111 111111111111 0
+	res = Sprintf([]byte{0xfe, 0xff}, "%-.0(fields)", status)
+	expected = "mode=7 count=4095 flags=()"
+	if res != expected {
+		t.Logf("fields expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	for _, bad := range [][]BitField{
+		{{Name: "a", Bits: 12}, {Name: "b", Bits: 12}},
+		{{Name: "a", Bits: -1}},
+		{{Name: "a", Bits: 65}},
+	} {
+		res = Sprintf([]byte{0x12, 0x34, 0x56}, "%.0(fields) %1x", bad)
+		expected = BadWidth + "2 56"
+		if res != expected {
+			t.Logf("fields %v expected %q, res %q", bad, expected, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestBitInt(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x4a}, "%.3d %.5d", "2 10"},
+		{[]byte{0x4a, 0x80}, "%.3d %.6d %.7d", "2 21 0"},
+		{[]byte{0xf0, 0x01}, "%.4d %1d", "15 1"},
+		{[]byte{0xe0}, "%+.3d %.0d", "-1 0"},
+		{[]byte{0x60}, "%+.3d", "3"},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, "%.64d", "18446744073709551615"},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, "%+.64d", "-1"},
+		{[]byte{0xff}, "%.65d", "%%BADWIDTH%65"},
+		{[]byte{0xff}, "%.9d", "%%EOF%"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestPopCount(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x00}, "%(popcount)", "0"},
+		{[]byte{0xff, 0x01}, "%(popcount) %(popcount)", "8 1"},
+		{[]byte{0xf0, 0x0f, 0x81}, "%3(popcount)", "10"},
+		{[]byte{0x81, 0x0f}, "%-2(popcount)", "6"},
+		{[]byte{0x01}, "%0(popcount)", "0"},
+		{[]byte{0x01}, "%2(popcount)", "%%EOF%"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestLSBFirst(t *testing.T) {
+	var tests = []struct {
+		buf []byte
+		fmt string
+		msb string
+		lsb string
+	}{
+		{[]byte{0x01}, "%1(bits)", "0", "1"},
+		{[]byte{0xb4}, "%.3d %.5d", "5 20", "4 22"},
+		{[]byte{0x0f, 0xf0}, "%.4d %.8d %.4d", "0 255 0", "15 0 15"},
+		{[]byte{0x01, 0x80}, "%.9d|%1d", "3|%%EOF%", "1|%%EOF%"},
+		{[]byte{0xff, 0x12}, "%.4d %1x", "15 12", "15 12"},
+		{[]byte{0x05}, "%+.3d", "0", "-3"},
+		{[]byte{0x3f, 0x80, 0x00, 0x00}, "%(bitfloat)", "1", "1"},
+	}
+	d := NewDumper()
+	for _, tt := range tests {
+		d.LSBFirst = false
+		msb := d.Sprintf(tt.buf, tt.fmt)
+		d.LSBFirst = true
+		lsb := d.Sprintf(tt.buf, tt.fmt)
+		if msb != tt.msb || lsb != tt.lsb {
+			t.Logf("format %q: expected %q and %q, res %q and %q", tt.fmt, tt.msb, tt.lsb, msb, lsb)
+			t.Fail()
+		}
+	}
+}
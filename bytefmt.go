@@ -5,49 +5,595 @@ consume bytes from the byte slice buf passed as an arg. The width part of a
 format verb specifies the number of bytes to consume of an array. The following
 format letters are understood:
 
-	%p	hex dump bytes using encoding/hex.Dump
-	%q  print a go quoted string
-	%s  print a string
-	%d	print a decimal int (max width 8)
+	%p	hex dump bytes using encoding/hex.Dump, with the # flag without
+	    the offset column and with the Dumper's LineBytes per line
+	%q  print a go quoted string, with the # flag of width UTF-8
+	    encoded runes instead of bytes
+	%C	print a C quoted string, bytes outside of printable ASCII
+	    without a C escape as \xHH
+	%s  print a string, with the # flag one preceded by a length of
+	    width (default 1) bytes
+	%Z	print a NUL terminated C string. A width is the size of a NUL
+	    padded field, which is consumed in full
+	%a	print a string with bytes outside of printable ASCII as ´.´
+	%l	print a Go []byte literal, e.g. []byte{0x01, 0x02}
+	%h	print bytes as two digit hex joined by the Dumper's
+	    HexSeparator, ':' by default (e.g. de:ad:be:ef)
+	%B	print base64, URL safe base64 with the # flag
+	%c	print width (default 1) UTF-8 encoded runes, an invalid
+	    encoding as U+FFFD consuming a single byte
+	%d	print a decimal int (max width 8). If prec is used without a
+	    width, it is the number of bits (max 64) to consume instead,
+	    continuing within the current byte. A zero width or a zero
+	    prec without a width consumes nothing and prints 0.
+	%v	print an unsigned decimal int of width (default 1, max 8)
+	    bytes
+	%w	print an unsigned LEB128 (protobuf) varint of up to 10 bytes.
+	    A longer one is flagged with BadValue, consuming it up to its
+	    last byte.
+	%W	print a zigzag encoded signed varint (protobuf sint32/sint64)
 	%x	print hex int (max width 8)
+	    With a width and a smaller nonzero prec, %d and %x consume a
+	    width byte slot but only the low prec bytes of it are the int,
+	    the last ones or with the - flag the first ones (e.g. %8.4d). A
+	    zero prec is ignored, a prec not smaller than the width is
+	    flagged with BadWidth, skipping width bytes.
+	%o	print octal int (max width 8)
+	%f	print IEEE 754 float, width 4 (default), 8 or 2 for half
+	    precision, or 10 for x87 extended precision (e.g. the AIFF
+	    sample rate). prec is the number of decimals, the shortest
+	    representation without one. Other widths are flagged with
+	    BadWidth, skipping width bytes.
+	%E	print a float like %f in scientific notation, e.g. 1.5e+00
+	%g	print a float like %f with prec significant digits
+	%j	print a fixed point (Q format) int of width (default 4) bytes
+	    with prec fraction bits, by default half of its bits
 	%b	print binary int (max width 8). If prec is used, it is an index
 	    for an argument mapping bit values to string names.
-	%e	print enumerated type, precision field is argument index
+	%e	print enumerated type of width (default 4, max 8) bytes,
+	    precision field is argument index. An unmapped zero value is
+	    printed as the Dumper's ZeroLabel if set, other unmapped values
+	    are flagged with BadValue if the Dumper has StrictEnum set,
+	    which also flags a width too small for a key of the map with
+	    BadWidth, consuming nothing. If the Dumper has ColorMode set,
+	    an argument of type Colors right after the enum map colors its
+	    labels with ANSI SGR codes (e.g. "1;31"); any other argument
+	    there is left alone, so it can be the map of the next %e.
+	    The # flag prints the raw value followed by its label, e.g.
+	    2 (Two), and the raw value alone if it is not mapped.
+	%I	print IP address, width 4 (default) for IPv4 or 16 for IPv6,
+	    other widths are flagged with BadWidth and skipped
+	%M	print MAC address, width 6 (default) or 8 for EUI-64, other
+	    widths are flagged with BadWidth and skipped
+	%U	print 16 byte UUID in its canonical hyphenated form, with the #
+	    flag a Microsoft GUID with little endian first three fields,
+	    other widths are flagged with BadWidth and skipped
+	%T	print Unix time, width 4 (default) or 8 bytes of seconds or,
+	    with the # flag, milliseconds or, with the _ flag, nanoseconds
+	    (e.g. %_8T). prec is the argument index of a time layout
+	    string, RFC 3339 in UTC by default
+	%N	print packed BCD digits of width (default 4) bytes, skipping
+	    0xf filler nibbles and printing other ones > 9 as ´?´. The #
+	    flag swaps the nibbles of each byte (TBCD), the + flag reads a
+	    COBOL style signed packed decimal
+	%K	mark the start of a checksummed region, printing nothing. The
+	    region starts at offset 0 by default
+	%k	print the checksum of the bytes from the mark up to here in
+	    hex, prec is the argument index of the algorithm name (crc16,
+	    crc32, crc32c or adler32), crc32 by default
+	%z	skip width (default 1) bytes, printing nothing
+	%|	continue at the next offset that is a multiple of width,
+	    printing nothing (e.g. %4| for 4 byte alignment)
+	%O	print the offset of the next byte in decimal, or in hex with
+	    the # flag, zero padded to width digits, consuming nothing
+	%L	print the string argument selected by prec (default 0), e.g.
+	    a label looked up at run time, consuming nothing
+	%@	continue at the absolute offset width (default 0), printing
+	    nothing
 	%t	template map, width is length of int, prec is argument index
 	%i	scaled integer, prec is arguemt index of float64 scale factor
+	%r	print an int (max width 8) in the radix given by prec, 2 to
+	    36 (e.g. %4.36r), decimal without one
 
-	The %x and %d formats can be modified to use intel byte order using a
-	leading ´-´ sign in the width field (e.g. %-4d).
+	The %v, %x, %o, %b, %r, %d, %e, %f, %T and %N formats can be modified
+	to use intel byte order using a leading ´-´ sign in the width field
+	(e.g. %-4d). A leading ´>´ explicitly selects the default big endian
+	order (e.g. %>4d), if both are given the last one wins. A leading ´+´
+	sign makes %d, %i, %j, %T, %x, %o, %b and %r interpret the bytes as a
+	two's complement signed int (e.g. %+3d for a 3 byte int, %+x prints
+	-2a rather than ffffffd6). Enumerations and flags are always unsigned.
+	A leading zero in the width field makes %x, %o and %b print as many
+	digits as width bytes can hold, as an unsigned int unless the ´+´ flag
+	is given (e.g. %04x prints 8 hex digits). Asking for an int wider than
+	8 bytes stops formatting with BadWidth followed by the width.
+
+	The %p, %q, %C, %s, %a, %l, %h and %B formats consume the rest of the
+	input if no width is given. A precision then leaves out that many
+	trailing bytes (e.g. %.4s%4x for a body followed by a 4 byte
+	checksum).
+
+	A leading ´,´ flag groups the digits of %d by thousands with the
+	Dumper's DigitSeparator (e.g. %,8d prints 1,000,000 for 1000000).
+
+	A leading ´=´ flag formats the bytes without consuming them, so the
+	next format sees them again (e.g. %=4d (%4x) prints a 4 byte int in
+	decimal and in hex).
+
+	With a leading ´_´ flag, the precision of %s, %q, %C and %a is instead
+	the display width of the string, which is padded with spaces or cut to
+	that many characters (e.g. %_8.10s prints 8 bytes in a 10 column
+	field).
+
+	Width and precision may be given in hex with a 0x prefix and upper
+	case digits or in binary with a 0b prefix (e.g. %0x10s or %0b100d). A
+	0x or 0b not followed by such digits is a zero width %x or %b. A ´*´
+	width is taken from the next int argument not yet used by a ´*´,
+	skipping arguments of other types (e.g. %*s), a name in braces is
+	looked up in the first map[string]int argument (e.g. %{headerLen}s).
+
+	A format followed by ´*´ and a count is repeated that many times,
+	separated by the Dumper's Separator (e.g. %2d*8 for an array of eight
+	2 byte ints). Followed by ´...´ instead, it is repeated until the
+	input is exhausted (e.g. %2d... for a trailing array of 2 byte ints).
+	A last value cut short prints Truncated, unless the Dumper has
+	IgnorePartial set. Older versions printed a ´...´ following a format
+	as is, write it as ´.%0z..´ to keep it literal text (e.g. %8s.%0z..
+	prints the string followed by ...).
+
+	Formats reading bits, such as %.3d, take them most significant bit
+	first, continuing within the current byte. A Dumper with LSBFirst set
+	takes them least significant bit first instead.
+
+	The lines of multi-line output, such as the hex dump of %p, are
+	indented by the Dumper's Indent.
+
+Formats that are too specialised for a letter of their own are available as
+named verbs, written as the verb name in parentheses after the usual flags,
+width and precision (e.g. %(ip6prefix)). Some named verbs take additional
+numeric parameters after a colon, separated by commas (e.g. %4.3(fixrow:16)).
+The following names are understood:
+
+	ip6prefix	16 byte IPv6 address followed by a prefix length byte,
+		printed as addr/len
+	bcdtime	6 byte packed BCD YY MM DD HH MM SS date time printed as
+		ISO 8601, 20YY unless the # flag selects 19YY
+	compsize	compressed and uncompressed size fields (default width 4)
+		printed as comp/uncomp (ratio%), skipping the compressed
+		data. prec is the number of decimals of the ratio
+	reserved	int printed in hex (default width 4). prec is the argument
+		index of an int64 mask of reserved bits, any of which that
+		are set are listed after the value
+	fixrow	row of prec signed fixed point numbers of width bytes
+		(default 4) each, printed bracketed. The parameter is the
+		number of fraction bits, default half the element bits
+	parity	low 7 bits of a byte in decimal, flagged if bit 7 does not
+		give even parity (odd parity with the # flag)
+	bits	unsigned decimal of the next width (default 1) bits
+	rice	Golomb-Rice coded integer with parameter prec, quotient in
+		unary as one bits ended by a zero (# flag: zero bits ended
+		by a one)
+	present	presence bitmap of width (default 8) bits followed by a
+		value for each set bit in the sub-format argument indexed
+		by prec, printed as field[i]=value
+	mp4matrix	QuickTime/MP4 3x3 matrix of 4 byte fixed point elements,
+		16.16 except for the last column in 2.30
+	kvmap	count followed by that many length prefixed key and value
+		pairs printed as key=value lines. Count and lengths are
+		width (default 1) bytes
+	fixsplit	signed fixed point number stored as a width (default 2)
+		byte two's complement integer part and a prec (default 1)
+		byte fraction part
+	enums	prec enumerated values of width (default 1) bytes joined,
+		the parameter is the argument index of the map
+	exptime	unit exponent byte followed by a width (default 8) byte
+		count of 10^-exp seconds since the Unix epoch, printed as
+		RFC 3339
+	rgba16f	pixel of four 2 byte half precision float channels printed
+		as (r, g, b, a), prec is the number of decimals
+	deltas	count, base value and count-1 signed deltas of width
+		(default 2) bytes, printed as the reconstructed values
+	gain	signed fixed point gain in dB of width (default 2) bytes,
+		the parameter is the fraction bits (default 8). The # flag
+		prints the linear amplitude, prec is the number of decimals
+	signhex	two's complement int of width (default 4) bytes printed in
+		decimal and as its raw hex encoding, e.g. -2 (0xfffe)
+	crcblob	length prefixed blob with a width (default 2) byte length,
+		printed as len=N crc=0x... and skipped. prec is the argument
+		index of the algorithm name: crc16 (CRC-16/ARC), crc32
+		(default), crc32c or adler32
+	bounded	signed fixed point number of width (default 2) bytes, the
+		parameter is the fraction bits (default 8). Values below the
+		float64 argument at prec or above the one at prec+1 are
+		marked, and clamped with the # flag
+	bitfloat	IEEE 754 float read from the bit cursor, so it need not be
+		byte aligned. Width 4 (default) for float32, 8 for float64
+	tagged	tag byte and width (default 1) byte length prefixed value
+		formatted with the format for the tag in the map argument
+		selected by prec, hex dumped for unknown tags
+	norm	normalized integer of width (default 2) bytes printed as
+		UNORM in 0..1, or with the # flag as SNORM in -1..1
+	dosdate	2 byte DOS/FAT date printed as ISO 8601, or with width 4 a
+		time word followed by a date word as in ZIP headers printed
+		as date and time. The fields are not validated
+	agg	aggregate of a count prefixed array of width (default 2)
+		byte ints, the parameter is one of sum (default), avg, min
+		or max. The + flag makes the elements signed
+	qauto	Q format descriptor byte, integer bits (including the sign)
+		in the high and fraction bits in the low nibble, followed by
+		the signed fixed point value in as many bytes as needed
+	minifloat	float with a sign bit, width (default 5) exponent bits and
+		prec (default 10) mantissa bits read from the bit cursor
+	grid	width (default 1) bytes drawn as rows of prec (default 8)
+		bits, '#' for set and '.' for clear bits
+	asciinums	delimited ASCII decimal numbers in width bytes (default
+		the rest), the delimiter is the string argument indexed by
+		prec, a comma by default
+	bcdpct	signed packed decimal percentage of width (default 2) bytes
+		with a trailing sign nibble and prec implied decimal places
+	utf16bom	UTF-16 string prefixed by its width (default 2) byte length
+		in bytes. A leading byte order mark selects the byte order
+		and is dropped, otherwise the intel flag applies
+	enumgroups	width (default 1) bytes as groups of prec (default 2) bits,
+		each looked up in the map argument given by the parameter
+	csv	prec elements of width (default 2) bytes as a CSV row, with
+		a 1 byte count first if prec is omitted. Elements are ints,
+		signed with the + flag, or strings with the # flag
+	taitime	width (default 8) byte count of TAI seconds since the Unix
+		epoch printed as UTC, less the int leap second offset
+		argument indexed by prec
+	palette	index of width (default 1) bytes into the color.Palette
+		argument selected by prec, printed as #RRGGBB
+	b64blob	length prefixed blob with a width (default 2) byte length in
+		base64, URL safe base64 with the # flag
+	delta	signed fixed point difference of width (default 2) bytes
+		with an explicit sign and the unit string argument indexed
+		by prec. The parameters are the fraction bits (default 8)
+		and the number of decimals
+	sentinel	ints of width (default 2) bytes up to the int64 sentinel
+		argument indexed by prec, or the end of the input
+	interp	signed fixed point number of width (default 2) bytes, the
+		parameter is the fraction bits (default 0), linearly
+		interpolated in the [][2]float64 table argument indexed by
+		prec and clamped to its ends
+	adc	unsigned ADC reading of width (default 2) bytes as a voltage
+		of the float64 reference argument indexed by prec. The
+		parameters are the resolution in bits (default all bits)
+		and the number of decimals
+	mime	sniffed content type of a length prefixed blob with a width
+		(default 2) byte length, with a hex preview for the # flag
+	hexfix	signed fixed point number of width (default 2) bytes with
+		prec (default 8) fraction bits in hex, e.g. 0x1.8 for 1.5
+	fields	width (default 2) byte word split into the subfields of the
+		[]BitField argument indexed by prec, most significant first,
+		printed as name=value pairs
+	asciitime	timestamp of width (default 15) ASCII bytes parsed with
+		the layout string argument indexed by prec, ASN.1
+		GeneralizedTime (e.g. 20240131123000Z) by default, printed as
+		RFC 3339 in UTC
+	popcount	number of set bits of width (default 1) bytes
+	signmag	sign-magnitude int of width bytes in decimal, the top bit
+		being the sign
+	excess	excess-K int of width bytes in decimal, the unsigned value
+		less the parameter K (default half the range, e.g. 128 for
+		%1(excess))
+	lendelim	protobuf length delimited field, a varint length and that
+		many bytes formatted with the string argument selected by
+		prec, or in hex as by %h
+	asciiint	ASCII number in a field of width (default the rest) bytes,
+		space padded, printed in decimal. The # flag reads hex digits
+	fourcc	four character code of width (default 4) bytes, such as a
+		RIFF chunk id, bytes outside of printable ASCII as ´.´
+	percent	int of width bytes as a percentage of the float64 argument
+		selected by prec, by default of the largest value of the
+		width. The parameter is the number of decimals (default 1),
+		e.g. %1.0(percent:0)
+	oid	ASN.1 object identifier of width (default the rest) bytes,
+		printed dotted (e.g. 1.2.840.113549)
+	filetime	Windows FILETIME, a width 8 count of 100 ns intervals since
+		1601 in little endian byte order unless the > flag is given,
+		printed like %T with the time layout argument selected by prec
+	bar	int of width bytes as a bar of block characters, filled to its
+		fraction of the float64 argument selected by prec as for
+		percent and clamped. The bar is as long as the int argument
+		after the maximum, 10 characters without a prec
+	duration	int of width bytes printed as a time.Duration (e.g.
+		1h30m0s). prec is the unit, 0 for ns, 1 for µs, 2 for ms, 3
+		for s (default), 4 for minutes and 5 for hours
+	vlq	big endian variable-length quantity as used by MIDI, 7 bits
+		per byte with the high bit set on all but the last, in decimal.
+		One that overflows an int64 is printed as BadValue, consuming
+		it up to its last byte.
+	entropy	Shannon entropy of width (default the rest) bytes in bits per
+		byte, 0 to 8. The parameter is the number of decimals
+		(default 2), e.g. %256(entropy:3)
+	ntp	NTP timestamp of width 8 (default), 32 bit seconds since 1900
+		and a 32 bit fraction, printed like %T with the time layout
+		argument selected by prec. Width 4 is the NTP short format of
+		16 bit seconds and fraction, printed as a time.Duration
+	size	unsigned int of width bytes as a byte size with binary
+		prefixes (e.g. 1.5 MiB), or decimal ones with the # flag
+		(e.g. 1.5 MB). prec is the number of decimals (default 1)
+	bytesum	8 bit sum of the bytes from the %K mark up to here, or their
+		XOR with the # flag. The parameter is the base (default 10),
+		16 prints hex as %k does (e.g. %(bytesum:16))
 */
 package bytefmt
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"io"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
 )
 
 var (
-	// UnknownFormat is suffixed by the unknown format letter
+	// UnknownFormat is suffixed by the unknown format letter. The
+	// misspelling is kept for compatibility, set it to "%%UNKNOWN%" for
+	// the correct one
 	UnknownFormat = "%%UNKOWN%"
+	// BadValue is suffixed by a decoded value that is out of range
+	// for the format
+	BadValue = "%%BADVALUE%"
+	// BadWidth is suffixed by a width that the format does not support
+	BadWidth = "%%BADWIDTH%"
+	// MissingVerb is suffixed by a format cut short by the end of the
+	// format string before its verb letter
+	MissingVerb = "%%NOVERB%"
+	// BadArg is suffixed by the index of a missing argument or one of the
+	// wrong type, formatting stops there
+	BadArg = "%%BADARG%"
+	// RepeatSeparator is printed between the repetitions of a format
+	// by Dumpers without a Separator.
+	//
+	// Deprecated: set the Separator of a Dumper instead.
+	RepeatSeparator = ", "
+	// Truncated is printed in place of a format that needs more bytes
+	// than are left in the input, formatting stops there
+	Truncated = "%%EOF%"
+	// OutputLimit is printed after output cut at Dumper.MaxOutput,
+	// formatting stops there
+	OutputLimit = "%%MAXOUTPUT%"
 )
 
-type dumper struct {
-	input      []byte
-	ii         int
+// Colors maps enum values to the ANSI SGR codes their %e labels are
+// colored with if the Dumper has ColorMode set. It is passed right
+// after the enum map it belongs to.
+type Colors map[int64]string
+
+// A TruncatedError reports a format that needs more bytes than are left
+// in the input.
+type TruncatedError struct {
+	Verb   string // the offending format, e.g. "%8d"
+	Offset int    // the read offset at which the input ran out
+}
+
+func (e *TruncatedError) Error() string {
+	return "bytefmt: " + e.Verb + " at offset " + strconv.Itoa(e.Offset) + " exceeds input"
+}
+
+// An ArgError reports a missing argument or one of the wrong type.
+type ArgError struct {
+	Verb  string // the offending format, e.g. "%.2e"
+	Index int    // the argument index
+}
+
+func (e *ArgError) Error() string {
+	return "bytefmt: " + e.Verb + " argument " + strconv.Itoa(e.Index) + " missing or of wrong type"
+}
+
+// A WidthError reports an int format wider than 8 bytes.
+type WidthError struct {
+	Verb  string // the offending format, e.g. "%16d"
+	Width int
+}
+
+func (e *WidthError) Error() string {
+	return "bytefmt: " + e.Verb + " width " + strconv.Itoa(e.Width) + " exceeds 8 bytes"
+}
+
+// An OutputLimitError reports output that would exceed Dumper.MaxOutput.
+type OutputLimitError struct {
+	Max int // the MaxOutput of the Dumper
+}
+
+func (e *OutputLimitError) Error() string {
+	return "bytefmt: output exceeds " + strconv.Itoa(e.Max) + " bytes"
+}
+
+// A Dumper holds the state of a formatting run. Reusing a Dumper across
+// calls saves allocating a new output buffer each time. A Dumper must
+// not be used by several goroutines at once, the package level
+// functions are safe for concurrent use.
+type Dumper struct {
+	// OnUnknownVerb, if set, returns the text printed in place of an
+	// unknown format letter c instead of UnknownFormat and c.
+	OnUnknownVerb func(c byte) string
+	// PassthroughUnknown makes unknown verbs print their format as is
+	// (e.g. %y as %y) instead of UnknownFormat, so that the output can
+	// be passed on to another templating layer.
+	PassthroughUnknown bool
+	// StrictEnum makes %e and (enums) flag values missing in their map
+	// with BadValue, and widths too small for the keys of their map
+	// with BadWidth.
+	StrictEnum bool
+	// ZeroLabel, if not empty, is printed by %e for a zero value that
+	// is not in the enum map.
+	ZeroLabel string
+	// ColorMode enables ANSI colors for %e labels that have a Colors
+	// argument.
+	ColorMode bool
+	// OnField, if set, is called after each top level format with its
+	// verb letter, or '(' for a named verb, the input offsets before and
+	// after it and the text it printed.
+	OnField func(verb byte, start, end int, text string)
+	// Separator is printed between the values of a format printing
+	// several ones, and between the repetitions of a format. Empty
+	// means RepeatSeparator, ", " by default.
+	Separator string
+	// NoSeparator makes such values follow each other without a
+	// separator.
+	NoSeparator bool
+	// FlagSeparator is printed between the names of the set bits of
+	// %b and (reserved). Empty means "|".
+	FlagSeparator string
+	// HexSeparator is printed between the bytes of %h. Empty means ":".
+	HexSeparator string
+	// DigitSeparator is printed between the groups of three digits of
+	// %d with the , flag. Empty means ",".
+	DigitSeparator string
+	// DefaultIntWidth is the width of the %d, %x, %o, %b, %r, %e, %t
+	// and %i formats if none is given. Zero means 4.
+	DefaultIntWidth int
+	// Strict makes bad values and widths, unknown verbs and malformed
+	// formats stop formatting with a *FormatError instead of printing a
+	// marker and going on.
+	Strict bool
+	// StopOnError is like Strict, but prints the marker before
+	// stopping.
+	StopOnError bool
+	// IgnorePartial makes a format repeated to the end of the input
+	// stop before a last value cut short, leaving its bytes unconsumed,
+	// instead of printing Truncated.
+	IgnorePartial bool
+	// LSBFirst makes the formats reading bits, such as %.3d and
+	// (bits), take them from the least significant bit of each byte,
+	// the first one read being the lowest bit of the value as in
+	// DEFLATE. By default they are taken most significant bit first.
+	LSBFirst bool
+	// LineBytes is the number of bytes per line of %#p. Zero means 16.
+	LineBytes int
+	// Indent is printed before each line of multi-line output such as
+	// the hex dump of %p. The first line is only indented if the output
+	// so far ends with a newline or is empty.
+	Indent string
+	// MaxOutput, if positive, caps the output at that many bytes,
+	// followed by OutputLimit. Formatting stops there with an
+	// *OutputLimitError.
+	MaxOutput int
+
+	spec
+	input   []byte
+	ii      int
+	bit     uint      // bits already consumed of input[ii]
+	mark    int       // start of the region summed by %k
+	num     [65]byte  // scratch space for formatting an int
+	src     io.Reader // source of further input, if any
+	w       io.Writer // output written as it grows, if any
+	written int       // bytes written to w
+	base    int       // bytes in buf before the output, from AppendfTo
+	werr    error     // first error writing to w
+	midLine bool      // output written to w ends within a line
+	ctx     context.Context
+	depth   int               // nesting of doDump
+	argi    int               // next argument taken by a * width
+	pos     int               // position in the format string of the current format
+	at      io.ReaderAt       // input following buf, see NewReaderDumper
+	verbs   map[byte]VerbFunc // verbs added by RegisterVerb
+	keep    bool              // the output of the current format may be taken back
+	buf     bytes.Buffer
+}
+
+// spec holds the flags, width and precision of the format being
+// processed.
+type spec struct {
 	prec       int
 	precValid  bool
 	width      int
 	widthValid bool
-	intel      bool // intel byte order for multibyte ints
+	widthArg   bool   // width is taken from an argument
+	widthName  string // name of the width in a map[string]int argument
+	intel      bool   // intel byte order for multibyte ints
+	big        bool   // big endian byte order asked for with >
+	signed     bool   // two's complement ints
 	altFlag    bool
-	buf        bytes.Buffer
+	zeroPad    bool     // zero pad ints to the digits of their width
+	pad        bool     // prec is the display width of strings
+	peek       bool     // leave the consumed bytes to the next format
+	group      bool     // group the digits of %d by thousands
+	params     []string // parameters of a named verb
+	verb       string   // the format being processed
+}
+
+// dump runs doDump, turning an abort of formatting into an error with
+// stopped.
+func (d *Dumper) dump(fmt string, a []interface{}) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = d.stopped(e)
+		}
+	}()
+	d.doDump(fmt, a)
+	return nil
+}
+
+// stopped returns the error for the recovered panic e, turning a read
+// past the end of the input into the Truncated marker and a
+// *TruncatedError, an int wider than 8 bytes into BadWidth and a
+// *WidthError, a bad argument into BadArg and an *ArgError, and output
+// over MaxOutput into OutputLimit and an *OutputLimitError. Other
+// panics are passed on.
+func (d *Dumper) stopped(e interface{}) error {
+	switch e := e.(type) {
+	case *TruncatedError:
+		d.buf.WriteString(Truncated)
+		return e
+	case *WidthError:
+		d.buf.WriteString(BadWidth + strconv.Itoa(e.Width))
+		return e
+	case *ArgError:
+		d.buf.WriteString(BadArg + strconv.Itoa(e.Index))
+		return e
+	case *OutputLimitError:
+		d.buf.WriteString(OutputLimit)
+		return e
+	case *FormatError:
+		return e
+	case *abortError:
+		return e.err
+	}
+	panic(e)
+}
+
+// truncated aborts formatting if n more bytes are not available.
+func (d *Dumper) truncated(n int) {
+	if n < 0 || !d.fill(len(d.input)-d.buffered()+n) {
+		panic(&TruncatedError{Verb: d.verb, Offset: d.ii})
+	}
+}
+
+// fill makes sure the input holds at least n bytes, reading more from
+// the source reader if there is one. It reports whether it succeeded.
+func (d *Dumper) fill(n int) bool {
+	if n <= len(d.input) {
+		return true
+	}
+	if d.src == nil {
+		return false
+	}
+	b := make([]byte, n-len(d.input))
+	m, err := io.ReadFull(d.src, b)
+	d.input = append(d.input, b[:m]...)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		panic(&abortError{err})
+	}
+	return m == len(b)
+}
+
+// An abortError carries a failure of the source reader or the output
+// writer, or the cancellation of the context, out of doDump.
+type abortError struct {
+	err error
 }
 
 // A lot of the logic of this is copied from the fmt package.
-func (d *dumper) doDump(fmt string, a []interface{}) {
+func (d *Dumper) doDump(fmt string, a []interface{}) {
+	d.depth++
+	defer func() { d.depth-- }()
 	end := len(fmt)
 	//formatLoop:
 	for i := 0; i < end; {
@@ -57,194 +603,932 @@ func (d *dumper) doDump(fmt string, a []interface{}) {
 		}
 		if i > lasti {
 			d.buf.WriteString(fmt[lasti:i])
+			d.checkOutput()
 		}
-		i++
 		if i >= end {
 			break
 		}
-		c := fmt[i]
-		d.intel = false
-		d.altFlag = false
-		d.precValid = false
-		d.widthValid = false
-		d.width = 0
-		d.prec = 0
-		if c == '#' {
-			d.altFlag = true
-			i++
-			if i >= end {
-				break
+		d.pos = i
+		c, name, n, next, problem := d.spec.parse(fmt, i)
+		if problem != "" {
+			d.verb = fmt[i:]
+			switch problem {
+			case missingParen, missingBrace:
+				d.bad(UnknownFormat+fmt[next:], problem)
+			case incomplete:
+				d.bad(MissingVerb+fmt[i:], problem)
+			default:
+				d.bad("", problem)
 			}
-			c = fmt[i]
+			break
 		}
-		if c == '-' {
-			d.intel = true
-			i++
-			if i >= end {
-				break
-			}
-			c = fmt[i]
+		i = next
+		if d.widthArg {
+			d.argWidth(a)
 		}
-		if c >= '0' && c <= '9' {
-			d.width, d.widthValid, i = parsenum(fmt, i, end)
-			if i >= end {
+		s := d.spec
+		for r := 0; n < 0 || r < n; r++ {
+			if d.ctx != nil {
+				if err := d.ctx.Err(); err != nil {
+					// Drop the output not yet written to w.
+					d.buf.Reset()
+					panic(&abortError{err})
+				}
+			}
+			if n < 0 && d.bit == 0 && !d.available(1) {
 				break
 			}
-			c = fmt[i]
-		}
-		if c == '.' {
-			i++
-			if i >= end {
+			mark := d.outPos()
+			if r > 0 {
+				d.buf.WriteString(d.sep())
+				d.spec = s
+			}
+			from, out := d.consumed(), d.outPos()
+			ii, bit := d.ii, d.bit
+			if !d.field(c, name, a, n < 0 && d.IgnorePartial) {
+				if i := d.bufIndex(mark); i >= d.base {
+					d.buf.Truncate(i)
+				}
 				break
 			}
-			d.prec, d.precValid, i = parsenum(fmt, i, end)
-			if i >= end {
+			if s.peek {
+				d.ii, d.bit = ii, bit
+			}
+			if d.OnField != nil && d.depth == 1 {
+				d.OnField(c, from, d.consumed(), string(d.buf.Bytes()[d.bufIndex(out):]))
+			}
+			d.checkOutput()
+			// Only flush between the top level formats, so that
+			// the output of the current one stays in buf.
+			if d.w != nil && d.depth == 1 && d.buf.Len() >= flushSize {
+				if err := d.flush(); err != nil {
+					panic(&abortError{err})
+				}
+			}
+			if n < 0 && d.ii == ii && d.bit == bit {
+				// A format consuming nothing would never reach
+				// the end.
 				break
 			}
-			c = fmt[i]
 		}
+	}
+}
+
+// field formats a single value with the letter verb c or the named verb
+// name. With partial set, a value cut short by the end of the input is
+// not an error, field then reports false with the read offset restored.
+func (d *Dumper) field(c byte, name string, a []interface{}, partial bool) (ok bool) {
+	if partial {
+		ii, bit, keep := d.ii, d.bit, d.keep
+		d.keep = true
+		defer func() {
+			d.keep = keep
+			if e := recover(); e != nil {
+				if _, trunc := e.(*TruncatedError); !trunc {
+					panic(e)
+				}
+				d.ii, d.bit = ii, bit
+				ok = false
+			}
+		}()
+	}
+	if fn, ok := d.verbs[c]; ok && c != '(' {
+		d.doRegistered(fn)
+	} else if c == '(' {
+		d.doNamed(name, a)
+	} else {
+		d.doVerb(c, a)
+	}
+	return true
+}
+
+// Problems found by parse.
+const (
+	incomplete   = "incomplete format"
+	tooLong      = "width or precision too large"
+	missingParen = "missing )"
+	missingBrace = "missing }"
+)
+
+// parse parses the format starting with the % at fmt[start] into s. It
+// returns the verb letter, or '(' and the name with parameters of a
+// named verb, the repeat count, -1 for a format repeated to the end of
+// the input, and the index following the format. If
+// the format is malformed, problem describes why, and for a missing )
+// or } next is the index of the ( or {.
+func (s *spec) parse(fmt string, start int) (c byte, name string, n int, next int, problem string) {
+	end := len(fmt)
+	*s = spec{}
+	i := start + 1
+	if i >= end {
+		return 0, "", 0, end, incomplete
+	}
+	c = fmt[i]
+	for s.setFlag(c) {
 		i++
-		switch c {
-		case '%':
-			d.buf.WriteRune('%')
-		case 'p':
-			if !d.widthValid {
-				d.width = len(d.input) - d.ii
+		if i >= end {
+			return 0, "", 0, end, incomplete
+		}
+		c = fmt[i]
+	}
+	if c == '0' && i+1 < end && fmt[i+1] >= '0' && fmt[i+1] <= '9' {
+		s.zeroPad = true
+		i++
+		c = fmt[i]
+	}
+	if c == '*' || c == '{' {
+		s.widthArg, s.widthValid = true, true
+		if c == '{' {
+			j := strings.IndexByte(fmt[i:], '}')
+			if j < 0 {
+				return 0, "", 0, i, missingBrace
 			}
-			d.buf.WriteString(hex.Dump(d.input[d.ii : d.ii+d.width]))
-			d.ii += d.width
-		case 'q':
+			s.widthName = fmt[i+1 : i+j]
+			i += j
+		}
+		i++
+		if i >= end {
+			return 0, "", 0, end, incomplete
+		}
+		c = fmt[i]
+	} else if c >= '0' && c <= '9' {
+		s.width, s.widthValid, i = parsenum(fmt, i, end)
+		if !s.widthValid {
+			return 0, "", 0, end, tooLong
+		}
+		if i >= end {
+			return 0, "", 0, end, incomplete
+		}
+		c = fmt[i]
+	}
+	if c == '.' {
+		i++
+		if i >= end {
+			return 0, "", 0, end, incomplete
+		}
+		digit := fmt[i] >= '0' && fmt[i] <= '9'
+		s.prec, s.precValid, i = parsenum(fmt, i, end)
+		if digit && !s.precValid {
+			return 0, "", 0, end, tooLong
+		}
+		if i >= end {
+			return 0, "", 0, end, incomplete
+		}
+		c = fmt[i]
+	}
+	if c == '(' {
+		j := strings.IndexByte(fmt[i:], ')')
+		if j < 0 {
+			return 0, "", 0, i, missingParen
+		}
+		name = fmt[i+1 : i+j]
+		i += j
+	}
+	s.verb = fmt[start : i+1]
+	i++
+	n = 1
+	if i+1 < end && fmt[i] == '*' && fmt[i+1] >= '0' && fmt[i+1] <= '9' {
+		n, _, i = parsenum(fmt, i+1, end)
+	} else if strings.HasPrefix(fmt[i:], "...") {
+		n, i = -1, i+3
+	}
+	return c, name, n, i, ""
+}
+
+// doVerb formats the letter verb c.
+func (d *Dumper) doVerb(c byte, a []interface{}) {
+	switch c {
+	case '%':
+		d.buf.WriteRune('%')
+	case 'p':
+		d.restWidth()
+		if d.altFlag {
+			d.compactDump(d.fetchBytes(d.width))
+			break
+		}
+		d.hexDump(d.fetchBytes(d.width))
+	case 'q':
+		if d.altFlag && d.widthValid {
+			d.writePadded(strconv.Quote(string(d.fetchRunes(d.width))))
+			break
+		}
+		d.restWidth()
+		d.limitWidth()
+		d.writePadded(strconv.Quote(string(d.fetchBytes(d.width))))
+	case 'C':
+		d.restWidth()
+		d.limitWidth()
+		d.writePadded(cQuote(d.fetchBytes(d.width)))
+	case 's':
+		if d.altFlag {
 			if !d.widthValid {
-				d.width = len(d.input) - d.ii
+				d.width = 1
 			}
-			d.buf.WriteString(strconv.Quote(string(d.input[d.ii : d.ii+d.width])))
-			d.ii += d.width
-		case 's':
-			if !d.widthValid {
-				d.width = len(d.input) - d.ii
+			d.writePadded(string(d.fetchPrefixed()))
+			break
+		}
+		d.restWidth()
+		d.limitWidth()
+		if d.pad && d.precValid {
+			d.writePadded(string(d.fetchBytes(d.width)))
+		} else {
+			d.writeBytes(d.fetchBytes(d.width))
+		}
+	case 'x':
+		_, slot, bad := d.intSlot(c)
+		if bad {
+			d.badWidth(d.prec)
+			d.fetchBytes(d.width)
+			break
+		}
+		d.intWidth()
+		d.writeInt(d.fetchSlot(slot), 16)
+	case 'o':
+		d.intWidth()
+		x := d.fetchSigned()
+		d.writeInt(x, 8)
+	case 'd':
+		bits, slot, bad := d.intSlot(c)
+		if bits {
+			d.fmtBitInt()
+			break
+		}
+		if bad {
+			d.badWidth(d.prec)
+			d.fetchBytes(d.width)
+			break
+		}
+		d.intWidth()
+		d.writeDecimalInt(d.fetchSlot(slot))
+	case 'v':
+		if !d.widthValid {
+			d.width = 1
+		}
+		x := d.fetchInt()
+		d.buf.Write(strconv.AppendUint(d.num[:0], uint64(x), 10))
+	case 'w':
+		d.fmtVarint()
+	case 'W':
+		d.fmtZigzag()
+	case 'f', 'E', 'g':
+		d.fmtFloat(c)
+	case 'j':
+		d.fmtQ()
+	case 'a':
+		d.restWidth()
+		d.limitWidth()
+		d.writePadded(printable(d.fetchBytes(d.width)))
+	case 'l':
+		d.restWidth()
+		d.limitWidth()
+		d.buf.WriteString("[]byte{")
+		for i, b := range d.fetchBytes(d.width) {
+			if i > 0 {
+				d.buf.WriteString(", ")
 			}
-			d.buf.WriteString(string(d.input[d.ii : d.ii+d.width]))
-			d.ii += d.width
-		case 'x':
-			if !d.widthValid {
-				d.width = 4
+			d.buf.WriteString("0x")
+			d.buf.WriteByte(hexDigits[b>>4])
+			d.buf.WriteByte(hexDigits[b&0xf])
+		}
+		d.buf.WriteRune('}')
+	case 'h':
+		d.restWidth()
+		d.limitWidth()
+		for i, b := range d.fetchBytes(d.width) {
+			if i > 0 {
+				d.buf.WriteString(d.hexSep())
 			}
-			x := d.fetchInt()
-			d.buf.WriteString(strconv.FormatInt(x, 16))
-		case 'd':
-			if !d.widthValid {
-				d.width = 4
+			d.buf.WriteByte(hexDigits[b>>4])
+			d.buf.WriteByte(hexDigits[b&0xf])
+		}
+	case 'B':
+		d.restWidth()
+		d.writeBase64(d.fetchBytes(d.width))
+	case 'c':
+		d.fmtRunes()
+	case 'Z':
+		d.fmtCString()
+	case 'I':
+		d.fmtIP()
+	case 'M':
+		d.fmtMAC()
+	case 'U':
+		d.fmtUUID()
+	case 'T':
+		d.fmtUnixTime(a)
+	case 'N':
+		d.fmtBCD()
+	case 'K':
+		d.alignByte()
+		d.mark = d.ii
+	case 'k':
+		d.fmtChecksum(a)
+	case 'z':
+		if !d.widthValid {
+			d.width = 1
+		}
+		d.fetchBytes(d.width)
+	case '@':
+		d.seek(d.width)
+	case '|':
+		d.alignByte()
+		if d.width > 1 {
+			d.seek((d.ii + d.width - 1) / d.width * d.width)
+		}
+	case 'O':
+		base := 10
+		if d.altFlag {
+			base = 16
+		}
+		o := strconv.FormatInt(int64(d.ii), base)
+		for n := len(o); n < d.width; n++ {
+			d.buf.WriteRune('0')
+		}
+		d.buf.WriteString(o)
+	case 'L':
+		d.buf.WriteString(d.argString(a, d.prec))
+	case 'b':
+		d.intWidth()
+		if d.precValid {
+			d.writeFlags(d.fetchInt(), d.argMap(a, d.prec))
+		} else {
+			d.writeInt(d.fetchSigned(), 2)
+		}
+	case 'e':
+		d.intWidth()
+		var m map[int64]string
+		if d.precValid {
+			m = d.argMap(a, d.prec)
+			if !d.enumWidthOK(m) {
+				break
 			}
-			x := d.fetchInt()
-			d.buf.WriteString(strconv.FormatInt(x, 10))
-		case 'b':
-			if !d.widthValid {
-				d.width = 4
+		}
+		x := d.fetchInt()
+		if s, ok := m[x]; ok {
+			if d.altFlag {
+				d.buf.WriteString(strconv.FormatInt(x, 10) + " (")
 			}
-			x := d.fetchInt()
-			if d.precValid {
-				d.buf.WriteRune('(')
-				m := a[d.prec].(map[int64]string)
-				var needOr = false
-				for bit, s := range m {
-					if x&bit != 0 {
-						if needOr {
-							d.buf.WriteRune('|')
-						}
-						d.buf.WriteString(s)
-						needOr = true
-						x &^= bit
-					}
-				}
-				if x != 0 {
-					if needOr {
-						d.buf.WriteRune('|')
-					}
-					d.buf.WriteString("0x")
-					d.buf.WriteString(strconv.FormatInt(x, 16))
-				}
+			d.writeEnum(x, s, a)
+			if d.altFlag {
 				d.buf.WriteRune(')')
-			} else {
-				d.buf.WriteString(strconv.FormatInt(x, 2))
 			}
-		case 'e':
-			if !d.widthValid {
-				d.width = 4
-			}
-			x := d.fetchInt()
-			if d.precValid {
-				m := a[d.prec].(map[int64]string)
-				if s, ok := m[x]; ok {
-					d.buf.WriteString(s)
-				} else {
-					d.buf.WriteString(strconv.FormatInt(x, 10))
-				}
+			break
+		}
+		switch {
+		case x == 0 && d.ZeroLabel != "" && !d.altFlag:
+			d.buf.WriteString(d.ZeroLabel)
+		case d.StrictEnum && d.precValid:
+			d.badValue(strconv.FormatInt(x, 10))
+		default:
+			d.buf.WriteString(strconv.FormatInt(x, 10))
+		}
+	case 't':
+		d.intWidth()
+		x := d.fetchInt()
+		if d.precValid {
+			m := d.argMap(a, d.prec)
+			if s, ok := m[x]; ok {
+				d.doDump(s, a)
 			} else {
 				d.buf.WriteString(strconv.FormatInt(x, 10))
 			}
-		case 't':
-			if !d.widthValid {
-				d.width = 4
+		} else {
+			d.buf.WriteString(strconv.FormatInt(x, 10))
+		}
+	case 'r':
+		d.intWidth()
+		x := d.fetchSigned()
+		base := 10
+		if d.precValid {
+			base = d.prec
+		}
+		if base < 2 || base > 36 {
+			d.badValue(strconv.Itoa(base))
+			break
+		}
+		d.buf.Write(strconv.AppendInt(d.num[:0], x, base))
+	case 'i':
+		d.intWidth()
+		x := float64(d.fetchSigned())
+		if d.precValid {
+			factor := d.argFloat(a, d.prec)
+			x *= factor
+		}
+		d.buf.WriteString(strconv.FormatFloat(x, 'g', -1, 64))
+	default:
+		if d.OnUnknownVerb != nil {
+			d.buf.WriteString(d.OnUnknownVerb(c))
+			break
+		}
+		d.unknownVerb(string(c))
+	}
+}
+
+// setFlag records c if it is a flag character and reports whether it
+// was one.
+func (s *spec) setFlag(c byte) bool {
+	switch c {
+	case '#':
+		s.altFlag = true
+	case '-':
+		s.intel, s.big = true, false
+	case '>':
+		s.intel, s.big = false, true
+	case '+':
+		s.signed = true
+	case '_':
+		s.pad = true
+	case '=':
+		s.peek = true
+	case ',':
+		s.group = true
+	default:
+		return false
+	}
+	return true
+}
+
+// writeFlags prints the names in m of the bits set in x, and any
+// remaining bits in hex.
+func (d *Dumper) writeFlags(x int64, m map[int64]string) {
+	d.buf.WriteRune('(')
+	var needOr = false
+	for bit, s := range m {
+		if x&bit != 0 {
+			if needOr {
+				d.buf.WriteString(d.flagSep())
 			}
-			x := d.fetchInt()
-			if d.precValid {
-				m := a[d.prec].(map[int64]string)
-				if s, ok := m[x]; ok {
-					d.doDump(s, a)
-				} else {
-					d.buf.WriteString(strconv.FormatInt(x, 10))
-				}
-			} else {
-				d.buf.WriteString(strconv.FormatInt(x, 10))
+			d.buf.WriteString(s)
+			needOr = true
+			x &^= bit
+		}
+	}
+	if x != 0 {
+		if needOr {
+			d.buf.WriteString(d.flagSep())
+		}
+		d.buf.WriteString("0x")
+		d.buf.WriteString(strconv.FormatInt(x, 16))
+	}
+	d.buf.WriteRune(')')
+}
+
+// sep returns the separator between several values of a format.
+func (d *Dumper) sep() string {
+	switch {
+	case d.NoSeparator:
+		return ""
+	case d.Separator == "":
+		return RepeatSeparator
+	}
+	return d.Separator
+}
+
+// flagSep returns the separator between the names of set bits.
+func (d *Dumper) flagSep() string {
+	if d.FlagSeparator == "" {
+		return "|"
+	}
+	return d.FlagSeparator
+}
+
+// hexDump prints the hex dump of b made by hex.Dump, a few lines at a
+// time.
+func (d *Dumper) hexDump(b []byte) {
+	var lines bytes.Buffer
+	h := hex.Dumper(&lines)
+	for len(b) > 0 {
+		n := len(b)
+		if n > 256 {
+			n = 256
+		}
+		h.Write(b[:n])
+		b = b[n:]
+		d.writeLines(lines.String())
+		lines.Reset()
+	}
+	h.Close()
+	d.writeLines(lines.String())
+}
+
+// compactDump hex dumps b like hex.Dump, but without the offset column
+// and with LineBytes bytes per line.
+func (d *Dumper) compactDump(b []byte) {
+	n := d.LineBytes
+	if n <= 0 {
+		n = 16
+	}
+	for len(b) > 0 {
+		row := b
+		if len(row) > n {
+			row = row[:n]
+		}
+		b = b[len(row):]
+		line := make([]byte, 0, 4*n+4)
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				line = append(line, ' ')
 			}
-		case 'i':
-			if !d.widthValid {
-				d.width = 4
+			if i < len(row) {
+				line = append(line, hexDigits[row[i]>>4], hexDigits[row[i]&0xf])
+			} else {
+				line = append(line, ' ', ' ')
 			}
-			x := float64(d.fetchInt())
-			if d.precValid {
-				factor := a[d.prec].(float64)
-				x *= factor
+		}
+		line = append(line, " |"...)
+		for _, c := range row {
+			if c < 32 || c > 126 {
+				c = '.'
 			}
-			d.buf.WriteString(strconv.FormatFloat(x, 'g', -1, 64))
-		default:
-			d.buf.WriteString(UnknownFormat + string(c))
+			line = append(line, c)
 		}
+		d.writeLines(string(append(line, "|\n"...)))
 	}
 }
 
-func (d *dumper) fetchInt() int64 {
-	var val int64
-	if d.intel {
-		for w := d.width; w > 0; w-- {
-			val |= int64(d.input[d.ii]) << uint((d.width-w)*8)
-			d.ii++
+// writeLines prints the lines of s, each preceded by the Indent at the
+// start of a line.
+func (d *Dumper) writeLines(s string) {
+	for s != "" {
+		if d.Indent != "" && d.lineStart() {
+			d.buf.WriteString(d.Indent)
 		}
-	} else {
-		for w := d.width; w > 0; w-- {
-			val <<= 8
-			val |= int64(d.input[d.ii])
-			d.ii++
+		i := strings.IndexByte(s, '\n') + 1
+		if i == 0 {
+			i = len(s)
 		}
+		d.buf.WriteString(s[:i])
+		d.checkOutput()
+		d.flushLarge()
+		s = s[i:]
+	}
+}
+
+// lineStart reports whether the output so far ends with a newline or is
+// empty.
+func (d *Dumper) lineStart() bool {
+	b := d.buf.Bytes()
+	if len(b) == 0 {
+		return !d.midLine
 	}
+	return b[len(b)-1] == '\n'
+}
+
+// hexSep returns the separator between the bytes of %h.
+func (d *Dumper) hexSep() string {
+	if d.HexSeparator == "" {
+		return ":"
+	}
+	return d.HexSeparator
+}
+
+// writeEnum prints the label s of enum value x, colored if d has
+// ColorMode set and the argument after the enum map is a Colors with a
+// color for x.
+func (d *Dumper) writeEnum(x int64, s string, a []interface{}) {
+	if d.ColorMode && d.prec+1 < len(a) {
+		colors, _ := a[d.prec+1].(Colors)
+		if c, ok := colors[x]; ok {
+			d.buf.WriteString("\x1b[" + c + "m" + s + "\x1b[0m")
+			return
+		}
+	}
+	d.buf.WriteString(s)
+}
+
+// enumWidthOK reports whether d.width bytes can hold all keys of the
+// enum map m. If the Dumper has StrictEnum set and they cannot, the
+// width is flagged with BadWidth.
+func (d *Dumper) enumWidthOK(m map[int64]string) bool {
+	if !d.StrictEnum || d.width >= 8 {
+		return true
+	}
+	for k := range m {
+		if uint64(k) >= 1<<uint(8*d.width) {
+			d.badWidth(d.width)
+			return false
+		}
+	}
+	return true
+}
+
+// fetchInt consumes a d.width byte unsigned int. A width of 0 consumes
+// nothing and yields 0.
+func (d *Dumper) fetchInt() int64 {
+	if d.width > 8 {
+		panic(&WidthError{Verb: d.verb, Width: d.width})
+	}
+	d.alignByte()
+	d.truncated(d.width)
+	val := decodeInt(d.input[d.ii:d.ii+d.width], d.intel)
+	d.ii += d.width
 	return val
 }
 
+// DecodeInt returns the unsigned int of the first width bytes of b, in
+// little endian byte order if littleEndian is set, as fetched by %d. It
+// returns a *WidthError for a width over 8 and a *TruncatedError if b is
+// too short.
+func DecodeInt(b []byte, width int, littleEndian bool) (int64, error) {
+	verb := "%" + strconv.Itoa(width) + "d"
+	if littleEndian {
+		verb = "%-" + verb[1:]
+	}
+	if width > 8 {
+		return 0, &WidthError{Verb: verb, Width: width}
+	}
+	if width < 0 || width > len(b) {
+		return 0, &TruncatedError{Verb: verb, Offset: 0}
+	}
+	return decodeInt(b[:width], littleEndian), nil
+}
+
+// decodeInt returns the bytes of b as an unsigned int.
+func decodeInt(b []byte, littleEndian bool) int64 {
+	var val int64
+	for i := range b {
+		if littleEndian {
+			val |= int64(b[i]) << uint(8*i)
+		} else {
+			val = val<<8 | int64(b[i])
+		}
+	}
+	return val
+}
+
+// fetchSigned is like fetchInt, but sign extends the value from the most
+// significant consumed byte if the + flag was given.
+func (d *Dumper) fetchSigned() int64 {
+	x := d.fetchInt()
+	if d.signed {
+		x = signExtend(x, d.width)
+	}
+	return x
+}
+
+// fetchSlot is like fetchSigned, but with slot set, as reported by
+// intSlot, it consumes a width byte slot of which only the low prec bytes
+// hold the int, the last ones or with the - flag the first ones.
+func (d *Dumper) fetchSlot(slot bool) int64 {
+	if !slot {
+		return d.fetchSigned()
+	}
+	x := d.fetchInt()
+	d.width = d.prec
+	x &= int64(uint64(1)<<uint(8*d.width) - 1)
+	if d.signed {
+		x = signExtend(x, d.width)
+	}
+	return x
+}
+
+// intSlot reports how the verb c reads its int given a prec. bits is set
+// for a %d prec without a width, which is the number of bits to consume.
+// slot is set for a %d or %x width and a smaller nonzero prec, the int
+// being in the low prec bytes of a width byte slot, bad for a nonzero
+// prec that is not smaller than the width. A zero prec is ignored.
+func (d *Dumper) intSlot(c byte) (bits, slot, bad bool) {
+	if c != 'd' && c != 'x' || !d.precValid {
+		return false, false, false
+	}
+	if !d.widthValid {
+		return c == 'd', false, false
+	}
+	if d.prec == 0 {
+		return false, false, false
+	}
+	return false, d.prec < d.width, d.prec >= d.width
+}
+
+// signExtend interprets the low width bytes of x as a two's complement
+// number.
+func signExtend(x int64, width int) int64 {
+	shift := uint(64 - 8*width)
+	return x << shift >> shift
+}
+
+// fetchBytes consumes the next n bytes of the input.
+func (d *Dumper) fetchBytes(n int) []byte {
+	d.alignByte()
+	d.truncated(n)
+	b := d.input[d.ii : d.ii+n]
+	d.ii += n
+	return b
+}
+
+// intWidth defaults the width of an int format to DefaultIntWidth.
+func (d *Dumper) intWidth() {
+	if d.widthValid {
+		return
+	}
+	d.width = d.DefaultIntWidth
+	if d.width <= 0 {
+		d.width = 4
+	}
+}
+
+// restWidth defaults the width to the rest of the input, less a tail of
+// prec bytes if prec is given without the _ flag.
+func (d *Dumper) restWidth() {
+	if d.widthValid {
+		return
+	}
+	d.width = d.remaining()
+	if d.precValid && !d.pad {
+		d.truncated(d.prec)
+		d.width -= d.prec
+	}
+}
+
+// seek continues reading at the absolute offset off.
+func (d *Dumper) seek(off int) {
+	if !d.fill(off) {
+		panic(&TruncatedError{Verb: d.verb, Offset: off})
+	}
+	d.ii = off
+	d.bit = 0
+}
+
+// remaining returns the number of whole bytes left in the input,
+// reading all of the source reader if there is one.
+func (d *Dumper) remaining() int {
+	if d.src != nil {
+		b, err := io.ReadAll(d.src)
+		d.input = append(d.input, b...)
+		d.src = nil
+		if err != nil {
+			panic(&abortError{err})
+		}
+	}
+	return d.buffered()
+}
+
+// available reports whether n whole bytes are left in the input,
+// reading no more of the source reader than needed.
+func (d *Dumper) available(n int) bool {
+	if d.bit != 0 {
+		n++
+	}
+	return d.fill(d.ii + n)
+}
+
+// buffered returns the number of whole bytes left in the input read so
+// far.
+func (d *Dumper) buffered() int {
+	if d.bit != 0 {
+		return len(d.input) - d.ii - 1
+	}
+	return len(d.input) - d.ii
+}
+
 // parsenum converts ASCII to integer.  num is 0 (and isnum is false) if no number present.
+// A 0x prefix followed by upper case hex digits or a 0b prefix followed by
+// binary digits selects base 16 or 2.
 func parsenum(s string, start, end int) (num int, isnum bool, newi int) {
 	if start >= end {
 		return 0, false, end
 	}
-	for newi = start; newi < end && '0' <= s[newi] && s[newi] <= '9'; newi++ {
+	base := 10
+	if start+2 < end && s[start] == '0' {
+		switch {
+		case s[start+1] == 'x' && digitVal(s[start+2]) < 16:
+			base = 16
+			start += 2
+		case s[start+1] == 'b' && digitVal(s[start+2]) < 2:
+			base = 2
+			start += 2
+		}
+	}
+	for newi = start; newi < end && digitVal(s[newi]) < base; newi++ {
 		if tooLarge(num) {
 			return 0, false, end // Overflow; crazy long number most likely.
 		}
-		num = num*10 + int(s[newi]-'0')
+		num = num*base + digitVal(s[newi])
 		isnum = true
 	}
 	return
 }
 
+// digitVal returns the value of the decimal or upper case hex digit c,
+// or 16 if it is none.
+func digitVal(c byte) int {
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0')
+	case 'A' <= c && c <= 'F':
+		return int(c-'A') + 10
+	}
+	return 16
+}
+
+// hexDigits are the lower case hex digits.
+const hexDigits = "0123456789abcdef"
+
+// flushSize is the amount of output collected before it is written out
+// by Fprintf.
+const flushSize = 4096
+
+// flush writes the output collected so far to d.w. Once a write failed,
+// flush just returns that error.
+func (d *Dumper) flush() error {
+	if d.werr != nil {
+		return d.werr
+	}
+	b := d.buf.Bytes()
+	if len(b) > 0 {
+		d.midLine = b[len(b)-1] != '\n'
+	}
+	n, err := d.w.Write(b)
+	d.written += n
+	d.buf.Reset()
+	d.werr = err
+	return err
+}
+
+// flushLarge writes out the output of a large top level value while it
+// is printed to w, unless it may still be taken back or passed to
+// OnField.
+func (d *Dumper) flushLarge() {
+	if d.w == nil || d.depth != 1 || d.keep || d.OnField != nil || d.buf.Len() < flushSize {
+		return
+	}
+	if err := d.flush(); err != nil {
+		panic(&abortError{err})
+	}
+}
+
+// writeBytes prints b in flushSize pieces, see writeString.
+func (d *Dumper) writeBytes(b []byte) {
+	for len(b) > flushSize {
+		d.buf.Write(b[:flushSize])
+		b = b[flushSize:]
+		d.checkOutput()
+		d.flushLarge()
+	}
+	d.buf.Write(b)
+}
+
+// writeString prints s in flushSize pieces, so that a large value is
+// written out to w as it is printed.
+func (d *Dumper) writeString(s string) {
+	for len(s) > flushSize {
+		d.buf.WriteString(s[:flushSize])
+		s = s[flushSize:]
+		d.checkOutput()
+		d.flushLarge()
+	}
+	d.buf.WriteString(s)
+}
+
+// outPos returns the position in the output of the next byte printed,
+// counting the output already flushed to w.
+func (d *Dumper) outPos() int {
+	return d.written + d.buf.Len() - d.base
+}
+
+// bufIndex returns the index in buf of the output position pos, which
+// must not have been flushed to w yet.
+func (d *Dumper) bufIndex(pos int) int {
+	return pos - d.written + d.base
+}
+
+// checkOutput stops formatting with an *OutputLimitError if the output
+// exceeds MaxOutput, cutting it at the limit.
+func (d *Dumper) checkOutput() {
+	if d.MaxOutput <= 0 {
+		return
+	}
+	if over := d.outPos() - d.MaxOutput; over > 0 {
+		if over > d.buf.Len() {
+			over = d.buf.Len()
+		}
+		d.buf.Truncate(d.buf.Len() - over)
+		panic(&OutputLimitError{Max: d.MaxOutput})
+	}
+}
+
+// limitWidth cuts the width of a format printing at least a byte for
+// each byte it consumes to what fills the rest of MaxOutput, so that a
+// large value is not rendered in full only to be cut. A few bytes to
+// spare keep the output up to the limit the same.
+func (d *Dumper) limitWidth() {
+	if d.MaxOutput <= 0 || d.pad && d.precValid {
+		return
+	}
+	if n := d.MaxOutput - d.outPos() + utf8.UTFMax; d.width > n {
+		d.width = n
+	}
+}
+
+// consumed returns the read offset, counting a partially consumed
+// byte as a whole one.
+func (d *Dumper) consumed() int {
+	if d.bit > 0 {
+		return d.ii + 1
+	}
+	return d.ii
+}
+
 // tooLarge reports whether the magnitude of the integer is
 // too large to be used as a formatting width or precision.
 func tooLarge(x int) bool {
@@ -252,12 +1536,23 @@ func tooLarge(x int) bool {
 	return x > max || x < -max
 }
 
-// Fprintf dumps to the writer w.
+// Fprintf dumps to the writer w, writing the output in chunks as it
+// grows. A write error stops formatting. If a format needs more bytes than are
+// left in buf, the output ends with Truncated and err is a
+// *TruncatedError. An int format wider than 8 bytes ends the output
+// with BadWidth and a *WidthError, a missing argument or one of the
+// wrong type with BadArg and an *ArgError.
 func Fprintf(w io.Writer, buf []byte, fmt string, a ...interface{}) (n int, err error) {
-	var d dumper
-	d.input = buf
-	d.doDump(fmt, a)
-	n, err = w.Write(d.buf.Bytes())
+	n, _, err = FprintfN(w, buf, fmt, a...)
+	return
+}
+
+// FprintfN is like Fprintf, but also returns the number of bytes
+// consumed from buf.
+func FprintfN(w io.Writer, buf []byte, fmt string, a ...interface{}) (n int, consumed int, err error) {
+	d := getDumper()
+	n, consumed, err = d.FprintfN(w, buf, fmt, a...)
+	putDumper(d)
 	return
 }
 
@@ -266,10 +1561,180 @@ func Printf(buf []byte, fmt string, a ...interface{}) (n int, err error) {
 	return Fprintf(os.Stdout, buf, fmt, a...)
 }
 
+// FprintfContext is like Fprintf, but stops formatting with ctx.Err()
+// once ctx is done, dropping the output not yet written to w.
+func FprintfContext(ctx context.Context, w io.Writer, buf []byte, fmt string, a ...interface{}) (n int, err error) {
+	d := getDumper()
+	n, err = d.FprintfContext(ctx, w, buf, fmt, a...)
+	putDumper(d)
+	return
+}
+
+// Fprintln is like Fprintf, but appends a newline to the output.
+func Fprintln(w io.Writer, buf []byte, fmt string, a ...interface{}) (n int, err error) {
+	d := getDumper()
+	n, _, err = d.fprint(nil, w, buf, fmt, a, "\n")
+	putDumper(d)
+	return
+}
+
+// Println is like Printf, but appends a newline to the output.
+func Println(buf []byte, fmt string, a ...interface{}) (n int, err error) {
+	return Fprintln(os.Stdout, buf, fmt, a...)
+}
+
+// Sprintln is like Sprintf, but appends a newline to the output.
+func Sprintln(buf []byte, fmt string, a ...interface{}) string {
+	return Sprintf(buf, fmt, a...) + "\n"
+}
+
 // Sprintf dumps to a string.
 func Sprintf(buf []byte, fmt string, a ...interface{}) string {
-	var d dumper
-	d.input = buf
-	d.doDump(fmt, a)
-	return d.buf.String()
+	d := getDumper()
+	s := d.Sprintf(buf, fmt, a...)
+	putDumper(d)
+	return s
+}
+
+// SprintfN is like Sprintf, but also returns the number of bytes
+// consumed from buf, so that buf[consumed:] can be used to format
+// the next record.
+func SprintfN(buf []byte, fmt string, a ...interface{}) (string, int) {
+	d := getDumper()
+	s, consumed := d.SprintfN(buf, fmt, a...)
+	putDumper(d)
+	return s, consumed
+}
+
+// Appendf dumps to dst and returns the extended slice.
+func Appendf(dst []byte, buf []byte, fmt string, a ...interface{}) []byte {
+	d := getDumper()
+	dst = d.Appendf(dst, buf, fmt, a...)
+	putDumper(d)
+	return dst
+}
+
+// AppendfTo dumps to dst like Appendf, but formats directly into the
+// spare capacity of dst, which is only reallocated if the output does
+// not fit. It also returns the number of bytes consumed from buf and,
+// as Fprintf does, an error if formatting stopped early.
+func AppendfTo(dst []byte, buf []byte, fmt string, a ...interface{}) (out []byte, consumed int, err error) {
+	d := getDumper()
+	out, consumed, err = d.AppendfTo(dst, buf, fmt, a...)
+	putDumper(d)
+	return
+}
+
+// maxPooledBuf is the largest output buffer kept in dumperPool.
+const maxPooledBuf = 64 << 10
+
+// dumperPool holds the Dumpers used by the package level functions.
+var dumperPool = sync.Pool{New: func() interface{} { return new(Dumper) }}
+
+// getDumper returns a Dumper from dumperPool.
+func getDumper() *Dumper {
+	return dumperPool.Get().(*Dumper)
+}
+
+// putDumper returns d to dumperPool, dropping its state so that it
+// keeps no references to the input and output of the last call.
+func putDumper(d *Dumper) {
+	if d.buf.Cap() > maxPooledBuf {
+		return
+	}
+	d.Reset()
+	dumperPool.Put(d)
+}
+
+// NewDumper returns a Dumper ready for use. The zero value is ready
+// for use as well.
+func NewDumper() *Dumper {
+	return &Dumper{}
+}
+
+// Reset clears the state of d, keeping its exported settings, the
+// verbs added by RegisterVerb and the allocated output buffer.
+func (d *Dumper) Reset() {
+	// Clear the state in place, so that the buffer is not copied.
+	d.spec = spec{}
+	d.input, d.ii, d.bit, d.mark = nil, 0, 0, 0
+	d.src, d.w, d.written, d.base, d.werr, d.midLine = nil, nil, 0, 0, nil, false
+	d.ctx, d.depth, d.argi, d.pos, d.keep = nil, 0, 0, 0, false
+	d.buf.Reset()
+}
+
+// Fprintf is like the package level Fprintf, reusing d.
+func (d *Dumper) Fprintf(w io.Writer, buf []byte, fmt string, a ...interface{}) (n int, err error) {
+	n, _, err = d.FprintfN(w, buf, fmt, a...)
+	return
+}
+
+// FprintfN is like the package level FprintfN, reusing d.
+func (d *Dumper) FprintfN(w io.Writer, buf []byte, fmt string, a ...interface{}) (n int, consumed int, err error) {
+	return d.fprint(nil, w, buf, fmt, a, "")
+}
+
+// FprintfContext is like the package level FprintfContext, reusing d.
+func (d *Dumper) FprintfContext(ctx context.Context, w io.Writer, buf []byte, fmt string, a ...interface{}) (n int, err error) {
+	n, _, err = d.fprint(ctx, w, buf, fmt, a, "")
+	return
+}
+
+// fprint implements FprintfN and FprintfContext, finishing the output
+// with suffix. ctx may be nil.
+func (d *Dumper) fprint(ctx context.Context, w io.Writer, buf []byte, fmt string, a []interface{}, suffix string) (n int, consumed int, err error) {
+	d.Reset()
+	d.ctx = ctx
+	d.setInput(buf)
+	d.w = w
+	derr := d.dump(fmt, a)
+	d.buf.WriteString(suffix)
+	err = d.flush()
+	n = d.written
+	consumed = d.consumed()
+	if err == nil {
+		err = derr
+	}
+	return
+}
+
+// Sprintf is like the package level Sprintf, reusing d.
+func (d *Dumper) Sprintf(buf []byte, fmt string, a ...interface{}) string {
+	s, _ := d.SprintfN(buf, fmt, a...)
+	return s
+}
+
+// SprintfN is like the package level SprintfN, reusing d.
+func (d *Dumper) SprintfN(buf []byte, fmt string, a ...interface{}) (string, int) {
+	d.Reset()
+	d.setInput(buf)
+	d.dump(fmt, a)
+	return d.buf.String(), d.consumed()
+}
+
+// Appendf is like the package level Appendf, reusing d.
+func (d *Dumper) Appendf(dst []byte, buf []byte, fmt string, a ...interface{}) []byte {
+	d.Reset()
+	d.setInput(buf)
+	d.dump(fmt, a)
+	return append(dst, d.buf.Bytes()...)
+}
+
+// AppendfTo is like the package level AppendfTo, reusing d. MaxOutput
+// limits the output appended, not counting dst.
+func (d *Dumper) AppendfTo(dst []byte, buf []byte, fmt string, a ...interface{}) (out []byte, consumed int, err error) {
+	d.Reset()
+	own := d.buf
+	d.buf = *bytes.NewBuffer(dst)
+	d.base = len(dst)
+	defer func() { d.buf, d.base = own, 0 }()
+	d.setInput(buf)
+	err = d.dump(fmt, a)
+	return d.buf.Bytes(), d.consumed(), err
+}
+
+// WriteTo writes the output of the last Sprintf, SprintfN or Appendf
+// call on d to w, draining it, so that a Dumper is an io.WriterTo.
+func (d *Dumper) WriteTo(w io.Writer) (int64, error) {
+	return d.buf.WriteTo(w)
 }
@@ -2,6 +2,13 @@ package bytefmt
 
 import (
 	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -23,6 +30,44 @@ var tests = []struct {
 	{[]byte{0x1, 0x2, 0x3, 0x4}, "%4d", "16909060"},
 	{[]byte{0x1, 0x2, 0x3, 0x4}, "%-4d", "67305985"},
 	{[]byte{0x1, 0x2, 0x3, 0x4}, "%4b", "1000000100000001100000100"},
+	{[]byte{0x1, 0x2, 0x3, 0x4}, "%4o", "100401404"},
+	{[]byte{0xed, 0x1}, "%-2o", "755"},
+	{[]byte{0x0, 0x1, 0x2, 0x3}, "%04x", "00010203"},
+	{[]byte{0x0, 0x1, 0x2, 0x3}, "%-04x", "03020100"},
+	{[]byte{0x3, 0x2, 0x1, 0x0}, "%-04x", "00010203"},
+	{[]byte{0x5}, "%01b", "00000101"},
+	{[]byte{0xed, 0x1}, "%-02o", "000755"},
+	{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, "%08x", "ffffffffffffffff"},
+	{[]byte{0x1, 0x2}, "%0d%1x", "01"},
+	{[]byte("hello"), "%B", "aGVsbG8="},
+	{[]byte("hello"), "%2B %B", "aGU= bGxv"},
+	{[]byte{0xfb, 0xff}, "%B", "+/8="},
+	{[]byte{0xfb, 0xff}, "%#B", "-_8="},
+	{[]byte{0xff, 0x1}, "%v %v", "255 1"},
+	{[]byte{0x1, 0x2}, "%2v", "258"},
+	{[]byte{0x1, 0x2}, "%-2v", "513"},
+	{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, "%8v", "18446744073709551615"},
+	{[]byte{0x1}, "%2v", "%%EOF%"},
+	{[]byte{0x1, 0x2, 0x3, 0x4}, "%>4x", "1020304"},
+	{[]byte{0x1, 0x2, 0x3, 0x4}, "%>-4x", "4030201"},
+	{[]byte{0x1, 0x2, 0x3, 0x4}, "%->4x", "1020304"},
+	{[]byte{0xff, 0xfe}, "%+>2d", "-2"},
+	{[]byte("a\x00b\nc\x7f\xc3\xa4~ "), "%a", "a.b.c...~ "},
+	{[]byte("\x1bHi"), "%2a%s", ".Hi"},
+	{[]byte{0x1, 0x2, 0x3, 0x4}, "%0x4d", "16909060"},
+	{[]byte{0x1, 0x2, 0x3, 0x4}, "%0b10x %0x2d", "102 772"},
+	{[]byte{0x1, 0x2, 0x3, 0x4}, "%-0x4x", "4030201"},
+	{[]byte("0123456789abcdefXY"), "%0x10s%s", "0123456789abcdefXY"},
+	{[]byte{0x1, 0x2, 0x3, 0x4}, "%0x%0b%1d", "001"},
+	{[]byte{0x1, 0x2, 0x3, 0x4}, "%.0b1000d%1d", "12"},
+	{[]byte("body\x01\x02"), "%.2s %-2x", "body 201"},
+	{[]byte("body\x01\x02"), "%.0s", "body\x01\x02"},
+	{[]byte("ab"), "%.2q%s", `""ab`},
+	{[]byte("ab"), "%1z%.1a%s", "b"},
+	{[]byte("ab"), "%.3s", "%%EOF%"},
+	{[]byte{0x1, 0xab, 0xff}, "%l", "[]byte{0x01, 0xab, 0xff}"},
+	{[]byte{0x1, 0xab, 0xff}, "%1l %l", "[]byte{0x01} []byte{0xab, 0xff}"},
+	{[]byte{}, "%l", "[]byte{}"},
 }
 
 func TestSprintf(t *testing.T) {
@@ -61,6 +106,36 @@ func TestEnum(t *testing.T) {
 	}
 }
 
+func TestEnumWidth(t *testing.T) {
+	var enumValues = map[int64]string{
+		0x0102:             "short",
+		0x010203:           "medium",
+		0x0102030405060708: "long",
+	}
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x01, 0x02}, "%2.0e", "short"},
+		{[]byte{0x02, 0x01}, "%-2.0e", "short"},
+		{[]byte{0x02, 0x01}, "%2.0e", "513"},
+		{[]byte{0x01, 0x02, 0x03}, "%3.0e", "medium"},
+		{[]byte{0x03, 0x02, 0x01}, "%-3.0e", "medium"},
+		{[]byte{0x00, 0x01, 0x02, 0x03}, "%.0e", "medium"},
+		{[]byte{1, 2, 3, 4, 5, 6, 7, 8}, "%8.0e", "long"},
+		{[]byte{8, 7, 6, 5, 4, 3, 2, 1}, "%-8.0e", "long"},
+		{[]byte{1, 2, 3, 4, 5, 6, 7, 8, 9}, "%9.0e", "%%BADWIDTH%9"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt, enumValues)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
 func TestTemplate(t *testing.T) {
 	var templates = map[int64]string{
 		1: "%1x",
@@ -115,3 +190,1244 @@ func TestFlags(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestMediumInt(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x7f, 0xff, 0xff}, "%+3d", "8388607"},
+		{[]byte{0x80, 0x00, 0x00}, "%+3d", "-8388608"},
+		{[]byte{0xff, 0xff, 0xff}, "%+3d", "-1"},
+		{[]byte{0xff, 0xff, 0x7f}, "%+-3d", "8388607"},
+		{[]byte{0x00, 0x00, 0x80}, "%-+3d", "-8388608"},
+		{[]byte{0xff, 0xff, 0xff}, "%+-3d", "-1"},
+		{[]byte{0xff, 0xff, 0xff}, "%3d", "16777215"},
+		{[]byte{0x00, 0x00, 0x80}, "%-3d", "8388608"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestZeroLabel(t *testing.T) {
+	var enumValues = map[int64]string{
+		1: "One",
+		2: "Two",
+	}
+	d := NewDumper()
+	d.ZeroLabel = "none"
+	res := d.Sprintf([]byte{0x0, 0x2, 0x0, 0x3}, "%1.0e, %1.0e, %1e, %1.0e", enumValues)
+	expected := "none, Two, none, 3"
+	if res != expected {
+		t.Logf("enum expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf([]byte{0x0}, "%1.0e", enumValues)
+	expected = "0"
+	if res != expected {
+		t.Logf("enum expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	enumValues[0] = "Zero"
+	res = d.Sprintf([]byte{0x0}, "%1.0e", enumValues)
+	expected = "Zero"
+	if res != expected {
+		t.Logf("enum expected %q, res %q", expected, res)
+		t.Fail()
+	}
+}
+
+func TestInt40(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff}, "%5d", "1099511627775"},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff}, "%-5d", "1099511627775"},
+		{[]byte{0x80, 0x00, 0x00, 0x00, 0x00}, "%5d", "549755813888"},
+		{[]byte{0x7f, 0xff, 0xff, 0xff, 0xff}, "%+5d", "549755813887"},
+		{[]byte{0x80, 0x00, 0x00, 0x00, 0x00}, "%+5d", "-549755813888"},
+		{[]byte{0x00, 0x00, 0x00, 0x00, 0x80}, "%+-5d", "-549755813888"},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff}, "%+5d", "-1"},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff}, "%+-5d", "-1"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestEnumColor(t *testing.T) {
+	var states = map[int64]string{
+		1: "OK",
+		2: "FAIL",
+	}
+	var colors = Colors{
+		1: "32",
+		2: "1;31",
+	}
+	buf := []byte{0x1, 0x2, 0x3}
+	res := Sprintf(buf, "%1.0e %1.0e %1.0e", states, colors)
+	expected := "OK FAIL 3"
+	if res != expected {
+		t.Logf("enum expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	d := NewDumper()
+	d.ColorMode = true
+	res = d.Sprintf(buf, "%1.0e %1.0e %1.0e", states, colors)
+	expected = "\x1b[32mOK\x1b[0m \x1b[1;31mFAIL\x1b[0m 3"
+	if res != expected {
+		t.Logf("enum expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = d.Sprintf(buf, "%1.0e %1.0e", states, "not colors")
+	expected = "OK FAIL"
+	if res != expected {
+		t.Logf("enum expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	// A plain map after the enum map is the next enum, not colors.
+	modes := map[int64]string{2: "RUN"}
+	res = d.Sprintf(buf, "%1.0e %1.1e", states, modes)
+	expected = "OK RUN"
+	if res != expected {
+		t.Logf("enum expected %q, res %q", expected, res)
+		t.Fail()
+	}
+}
+
+func TestSigned(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0xff}, "%+1d", "-1"},
+		{[]byte{0x7f}, "%+1d", "127"},
+		{[]byte{0xff, 0xff}, "%+2d", "-1"},
+		{[]byte{0xff, 0xff}, "%2d", "65535"},
+		{[]byte{0x80, 0x00}, "%+2d", "-32768"},
+		{[]byte{0x00, 0x80}, "%+-2d", "-32768"},
+		{[]byte{0xff, 0xff, 0xff, 0xfe}, "%+d", "-2"},
+		{[]byte{0xff, 0xff, 0xff, 0xfe}, "%+-4d", "-16777217"},
+		{[]byte{0xff, 0xff, 0xfe, 0xff, 0xff, 0xff}, "%+-6d", "-65537"},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xfe}, "%+7d", "-2"},
+		{[]byte{0x80, 0, 0, 0, 0, 0, 0, 0}, "%+8d", "-9223372036854775808"},
+		{[]byte{0, 0, 0, 0, 0, 0, 0, 0x80}, "%+-8d", "-9223372036854775808"},
+		{[]byte{0xff, 0xfe}, "%+2.0i", "-0.5"},
+		{[]byte{0xff, 0xfe}, "%+2.1e", "65534"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt, 0.25, map[int64]string{-2: "minus two"})
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestSprintfN(t *testing.T) {
+	buf := []byte{0, 1, 0, 2, 0, 3}
+	var res []string
+	for len(buf) > 0 {
+		s, n := SprintfN(buf, "%2d")
+		res = append(res, s)
+		buf = buf[n:]
+	}
+	if strings.Join(res, " ") != "1 2 3" {
+		t.Logf("unexpected records %q", res)
+		t.Fail()
+	}
+	_, n := SprintfN([]byte{0xff, 0}, "%3(bits)")
+	if n != 1 {
+		t.Logf("partial byte: expected 1, res %d", n)
+		t.Fail()
+	}
+	var b bytes.Buffer
+	w, n, err := FprintfN(&b, []byte{1, 2, 3}, "%1d %1d")
+	if err != nil || w != 3 || n != 2 || b.String() != "1 2" {
+		t.Logf("FprintfN: %d %d %v %q", w, n, err, b.String())
+		t.Fail()
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0, 1, 2, 3}, "%8d", "%%EOF%"},
+		{[]byte{0, 1, 2, 3}, "%2d %4x", "1 %%EOF%"},
+		{[]byte{'a', 'b'}, "%3s", "%%EOF%"},
+		{[]byte{'a', 'b'}, "%1q %2q", "\"a\" %%EOF%"},
+		{[]byte{0xff}, "%6(bits) %3(bits)", "63 %%EOF%"},
+		{[]byte{}, "%1d tail", "%%EOF%"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+	var b bytes.Buffer
+	_, err := Fprintf(&b, []byte{0, 1, 2}, "%1d %4d")
+	e, ok := err.(*TruncatedError)
+	if !ok || e.Verb != "%4d" || e.Offset != 1 {
+		t.Logf("unexpected error %v", err)
+		t.Fail()
+	}
+	if b.String() != "0 %%EOF%" {
+		t.Logf("unexpected output %q", b.String())
+		t.Fail()
+	}
+}
+
+func TestDumperReuse(t *testing.T) {
+	d := NewDumper()
+	for i, tt := range tests[:4] {
+		res := d.Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("%d: expected %q, res %q", i, tt.expect, res)
+			t.Fail()
+		}
+	}
+	d.Sprintf([]byte{0xff}, "%6(bits)")
+	s, n := d.SprintfN([]byte{0, 2}, "%-2d")
+	if s != "512" || n != 2 {
+		t.Logf("state leaked into next run: %q %d", s, n)
+		t.Fail()
+	}
+	var b bytes.Buffer
+	if _, err := d.Fprintf(&b, []byte{1}, "%2d"); err == nil || b.String() != "%%EOF%" {
+		t.Logf("Fprintf: %v %q", err, b.String())
+		t.Fail()
+	}
+}
+
+func TestAppendf(t *testing.T) {
+	dst := []byte("log: ")
+	for i, tt := range tests {
+		res := Appendf(dst, tt.buf, tt.fmt, 2.0, map[int64]string{1: "one"})
+		if string(res[len(dst):]) != Sprintf(tt.buf, tt.fmt, 2.0, map[int64]string{1: "one"}) {
+			t.Logf("%d: Appendf differs from Sprintf: %q", i, res)
+			t.Fail()
+		}
+	}
+	d := NewDumper()
+	res := d.Appendf(d.Appendf(nil, []byte{1}, "%1d,"), []byte{2}, "%1d")
+	if string(res) != "1,2" {
+		t.Logf("unexpected %q", res)
+		t.Fail()
+	}
+}
+
+func TestWidthLimit(t *testing.T) {
+	buf := make([]byte, 16)
+	var tests = []struct {
+		fmt    string
+		expect string
+	}{
+		{"%8d", "0"},
+		{"%9d", "%%BADWIDTH%9"},
+		{"%1d %16x", "0 %%BADWIDTH%16"},
+		{"%-12b", "%%BADWIDTH%12"},
+		{"%10o tail", "%%BADWIDTH%10"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+	var b bytes.Buffer
+	_, err := Fprintf(&b, buf, "%16d")
+	if e, ok := err.(*WidthError); !ok || e.Verb != "%16d" || e.Width != 16 {
+		t.Logf("unexpected error %v", err)
+		t.Fail()
+	}
+}
+
+func TestSkipSeek(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{1, 0, 0, 2}, "%1d %2z%1d", "1 2"},
+		{[]byte{1, 2}, "%z%1d", "2"},
+		{[]byte{1, 2}, "%0z%1d", "1"},
+		{[]byte{0, 1, 2, 3}, "%4x %2@%2x %@%1d", "10203 203 0"},
+		{[]byte{0xff, 7}, "%3(bits) %1@%1d", "7 7"},
+		{[]byte{1, 2}, "%2@%s.", "."},
+		{[]byte{1, 2}, "%3z", "%%EOF%"},
+		{[]byte{1, 2}, "%1d %3@%1d", "1 %%EOF%"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestRepeat(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0, 1, 0, 2, 0, 3}, "%2d*3", "1, 2, 3"},
+		{[]byte{1, 0, 2, 0}, "[%-2d*2]", "[1, 2]"},
+		{[]byte{1, 2, 3}, "%1d*1 %1d*0%1d", "1 2"},
+		{[]byte{1, 2}, "%1d*x", "1*x"},
+		{[]byte{1, 2}, "%1d*", "1*"},
+		{[]byte{0xa5}, "%2(bits)*4", "2, 2, 1, 1"},
+		{[]byte("abcd"), "%s*2", "abcd, "},
+		{[]byte{0, 1, 2}, "%1.0e*3", "zero, one, 2"},
+		{[]byte{1, 2}, "%1d*3", "1, 2, %%EOF%"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt, map[int64]string{0: "zero", 1: "one"})
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestRepeatToEnd(t *testing.T) {
+	var tests = []struct {
+		buf     []byte
+		fmt     string
+		expect  string
+		partial string
+	}{
+		{[]byte{0, 1, 0, 2, 0, 3}, "%2d...", "1, 2, 3", "1, 2, 3"},
+		{[]byte{9, 0, 1, 0, 2}, "%1d: [%2d...]", "9: [1, 2]", "9: [1, 2]"},
+		{[]byte{9}, "%1d: [%2d...]", "9: []", "9: []"},
+		{[]byte{0, 1, 0, 2, 3}, "%2d...", "1, 2, %%EOF%", "1, 2"},
+		{[]byte{0, 1, 0, 2, 3}, "%2d...|%1x", "1, 2, %%EOF%", "1, 2|3"},
+		{[]byte{0xa5}, "%2(bits)...", "2, 2, 1, 1", "2, 2, 1, 1"},
+		{[]byte("abcd"), "%s...", "abcd", "abcd"},
+		{[]byte{1, 2}, "%0d...", "0", "0"},
+		{[]byte{1, 2}, "%=1d... %1d", "1 1", "1 1"},
+		{[]byte{1, 2}, "%1d..", "1..", "1.."},
+		{[]byte{1, 2}, "%1d.%0z..", "1...", "1..."},
+	}
+	d := NewDumper()
+	for _, tt := range tests {
+		d.IgnorePartial = false
+		res := d.Sprintf(tt.buf, tt.fmt)
+		d.IgnorePartial = true
+		partial := d.Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect || partial != tt.partial {
+			t.Logf("format %q: expected %q and %q, res %q and %q", tt.fmt, tt.expect, tt.partial, res, partial)
+			t.Fail()
+		}
+	}
+	// The values of a region may be flushed before the last one is cut
+	// short.
+	buf := append([]byte{0x89, 0x27}, bytes.Repeat([]byte{0, 'a'}, 2500)...)
+	buf = append(buf, 'x', 'b')
+	var b bytes.Buffer
+	d.Fprintf(&b, buf, "%.0(lendelim)|%1s", "%2s...")
+	if !strings.HasSuffix(b.String(), "\x00a|b") {
+		t.Logf("flushed region: unexpected tail %q", b.String()[b.Len()-10:])
+		t.Fail()
+	}
+}
+
+func TestOnUnknownVerb(t *testing.T) {
+	var seen []byte
+	d := NewDumper()
+	d.OnUnknownVerb = func(c byte) string {
+		seen = append(seen, c)
+		return "<" + string(c) + ">"
+	}
+	res := d.Sprintf([]byte{1}, "%!%1d%Y")
+	if res != "<!>1<Y>" || string(seen) != "!Y" {
+		t.Logf("unexpected %q, seen %q", res, seen)
+		t.Fail()
+	}
+	res = d.Sprintf(nil, "%(nosuch)")
+	if res != "%%UNKOWN%(nosuch)" {
+		t.Logf("unexpected %q", res)
+		t.Fail()
+	}
+}
+
+func TestPassthroughUnknown(t *testing.T) {
+	d := NewDumper()
+	d.PassthroughUnknown = true
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{1, 2}, "%1d %y %1d", "1 %y 2"},
+		{[]byte{1}, "{{.Name}} %-4y=%1d", "{{.Name}} %-4y=1"},
+		{[]byte{1}, "%(nosuch:1) %1d", "%(nosuch:1) 1"},
+		{[]byte{1}, "%% %1d", "% 1"},
+		{[]byte{1}, "%1d %4.", "1 %%NOVERB%%4."},
+	}
+	for _, tt := range tests {
+		res := d.Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+	d.Strict = true
+	if res := d.Sprintf([]byte{1}, "%y %1d"); res != "%y 1" {
+		t.Logf("strict: unexpected %q", res)
+		t.Fail()
+	}
+	d.OnUnknownVerb = func(c byte) string { return "?" }
+	if res := d.Sprintf(nil, "%y"); res != "?" {
+		t.Logf("OnUnknownVerb: unexpected %q", res)
+		t.Fail()
+	}
+}
+
+func TestRegisterVerb(t *testing.T) {
+	d := NewDumper()
+	d.RegisterVerb('y', func(b []byte, width int, littleEndian bool) (string, int, error) {
+		if width < 0 {
+			return "all", len(b), nil
+		}
+		if width > len(b) {
+			return "", 0, errors.New("short")
+		}
+		if littleEndian {
+			return "le", width, nil
+		}
+		return "be", width, nil
+	})
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{1, 2, 3}, "%2y %1d", "be 3"},
+		{[]byte{1, 2, 3}, "%-2y %1d", "le 3"},
+		{[]byte{1, 2, 3}, "%1d %y", "1 all"},
+		{[]byte{1}, "%2y", BadValue + "short"},
+	}
+	for _, tt := range tests {
+		res := d.Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+	d.Strict = true
+	var fe *FormatError
+	if _, err := d.Fprintf(io.Discard, []byte{1}, "%2y"); !errors.As(err, &fe) {
+		t.Logf("strict: unexpected error %v", err)
+		t.Fail()
+	}
+	d.Strict = false
+	d.OverrideVerb('d', func(b []byte, width int, littleEndian bool) (string, int, error) {
+		return "D", 1, nil
+	})
+	if res := d.Sprintf([]byte{1, 2}, "%d%d"); res != "DD" {
+		t.Logf("OverrideVerb: unexpected %q", res)
+		t.Fail()
+	}
+	for _, c := range []byte{'d', '5', '#', '(', ' ', '\n', 0x80, 'Y'} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Logf("RegisterVerb(%q): expected a panic", c)
+					t.Fail()
+				}
+			}()
+			var fn VerbFunc
+			if c != 'Y' {
+				fn = func(b []byte, width int, littleEndian bool) (string, int, error) { return "", 0, nil }
+			}
+			d.RegisterVerb(c, fn)
+		}()
+	}
+	data := make([]byte, 100000)
+	r := &countingReaderAt{r: bytes.NewReader(data)}
+	rd := NewReaderDumper(r)
+	rd.RegisterVerb('y', func(b []byte, width int, littleEndian bool) (string, int, error) {
+		return strconv.Itoa(len(b)), len(b), nil
+	})
+	if res := rd.Sprintf(nil, "%2y"); res != "2" || r.max != 2 {
+		t.Logf("reader: unexpected %q, read %d", res, r.max)
+		t.Fail()
+	}
+}
+
+func TestZeroWidth(t *testing.T) {
+	var tests = []struct {
+		fmt    string
+		expect string
+	}{
+		{"%0d%1d", "01"},
+		{"%-0d%1d", "01"},
+		{"%+0d%1d", "01"},
+		{"%0x%0o%0b%0v", "0000"},
+		{"%.0d%1d", "01"},
+		{"%0.0e %1.0e", "zero one"},
+		{"%.0d%3(bits)", "00"},
+	}
+	for _, tt := range tests {
+		res := Sprintf([]byte{1}, tt.fmt, map[int64]string{0: "zero", 1: "one"})
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+	for _, f := range []string{"%0d", "%-0x", "%.0d", "%0z"} {
+		if _, n := SprintfN([]byte{1}, f); n != 0 {
+			t.Logf("format %q consumed %d bytes", f, n)
+			t.Fail()
+		}
+	}
+}
+
+func TestStrictEnum(t *testing.T) {
+	m := map[int64]string{1: "one"}
+	d := NewDumper()
+	buf := []byte{1, 42, 1, 42}
+	if res := d.Sprintf(buf, "%1.0e %1.0e %1.2(enums)", m); res != "one 42 one, 42" {
+		t.Logf("lenient: unexpected %q", res)
+		t.Fail()
+	}
+	d.StrictEnum = true
+	if res := d.Sprintf(buf, "%1.0e %1.0e %1.2(enums)", m); res != "one %%BADVALUE%42 one, %%BADVALUE%42" {
+		t.Logf("strict: unexpected %q", res)
+		t.Fail()
+	}
+	if res := d.Sprintf(buf, "%1e"); res != "1" {
+		t.Logf("strict without map: unexpected %q", res)
+		t.Fail()
+	}
+}
+
+func TestStrictEnumWidth(t *testing.T) {
+	m := map[int64]string{1: "one", 0x1234: "wide"}
+	buf := []byte{0x12, 0x34, 0x01}
+	d := NewDumper()
+	if res := d.Sprintf(buf, "%1.0e %1.0e", m); res != "18 52" {
+		t.Logf("lenient: unexpected %q", res)
+		t.Fail()
+	}
+	d.StrictEnum = true
+	var tests = []struct {
+		fmt    string
+		expect string
+	}{
+		{"%2.0e %1x", "wide 1"},
+		{"%1.0e%2x", "%%BADWIDTH%11234"},
+		{"%1.2(enums)%3x", "%%BADWIDTH%1123401"},
+		{"%2.2(enums)", "wide, %%EOF%"},
+		{"%8.0e", "%%EOF%"},
+	}
+	for _, tt := range tests {
+		if res := d.Sprintf(buf, tt.fmt, m); res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+	neg := map[int64]string{-1: "minus one"}
+	if res := d.Sprintf([]byte{0xff}, "%1.0e", neg); res != "%%BADWIDTH%1" {
+		t.Logf("negative key: unexpected %q", res)
+		t.Fail()
+	}
+}
+
+type chunkWriter struct {
+	chunks []int
+	fail   error
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	if w.fail != nil && len(w.chunks) > 0 {
+		return 0, w.fail
+	}
+	w.chunks = append(w.chunks, len(p))
+	return len(p), nil
+}
+
+func TestFprintfChunks(t *testing.T) {
+	buf := bytes.Repeat([]byte{'x'}, 3*flushSize)
+	var w chunkWriter
+	n, consumed, err := FprintfN(&w, buf, "%0x1000s*3 end")
+	if err != nil || n != len(buf)+2*len(RepeatSeparator)+4 || consumed != len(buf) || len(w.chunks) != 4 {
+		t.Logf("unexpected %d %d %v %v", n, consumed, err, w.chunks)
+		t.Fail()
+	}
+	w = chunkWriter{fail: errors.New("full")}
+	n, consumed, err = FprintfN(&w, buf, "%0x1000s*3")
+	if err != w.fail || n != flushSize || consumed != 2*flushSize {
+		t.Logf("unexpected %d %d %v %v", n, consumed, err, w.chunks)
+		t.Fail()
+	}
+	// A single large value is written out as it is printed.
+	large := make([]byte, 1<<20)
+	for _, f := range []string{"%s", "%p", "%#p", "%q"} {
+		w = chunkWriter{}
+		Fprintf(&w, large, f)
+		for _, c := range w.chunks {
+			if len(w.chunks) < 2 || c > 2*flushSize {
+				t.Logf("format %q: unexpected chunks %v", f, w.chunks)
+				t.Fail()
+				break
+			}
+		}
+	}
+}
+
+func TestSeparator(t *testing.T) {
+	m := map[int64]string{1: "one", 2: "two"}
+	d := NewDumper()
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{1, 2, 3}, "%1d*3", "1 / 2 / 3"},
+		{[]byte{1, 2}, "%1.2(enums)", "one / two"},
+		{[]byte{5}, "%1.0b", "(one,0x4)"},
+	}
+	d.Separator = " / "
+	d.FlagSeparator = ","
+	for _, tt := range tests {
+		res := d.Sprintf(tt.buf, tt.fmt, m)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+	if res := Sprintf([]byte{1, 2}, "%1d*2"); res != "1, 2" {
+		t.Logf("default separator: unexpected %q", res)
+		t.Fail()
+	}
+	d.NoSeparator = true
+	if res := d.Sprintf([]byte{1, 2, 3}, "%1d*3"); res != "123" {
+		t.Logf("no separator: unexpected %q", res)
+		t.Fail()
+	}
+}
+
+func TestLn(t *testing.T) {
+	if res := Sprintln([]byte{0, 1}, "%2d"); res != "1\n" {
+		t.Logf("Sprintln: unexpected %q", res)
+		t.Fail()
+	}
+	var b bytes.Buffer
+	n, err := Fprintln(&b, []byte{0, 1}, "v=%2d")
+	if n != 4 || err != nil || b.String() != "v=1\n" {
+		t.Logf("Fprintln: unexpected %d %v %q", n, err, b.String())
+		t.Fail()
+	}
+	b.Reset()
+	_, err = Fprintln(&b, []byte{0}, "%2d")
+	if err == nil || b.String() != "%%EOF%\n" {
+		t.Logf("Fprintln truncated: unexpected %v %q", err, b.String())
+		t.Fail()
+	}
+}
+
+type cancelWriter struct {
+	bytes.Buffer
+	cancel func()
+}
+
+func (w *cancelWriter) Write(p []byte) (int, error) {
+	w.cancel()
+	return w.Buffer.Write(p)
+}
+
+func TestFprintfContext(t *testing.T) {
+	buf := bytes.Repeat([]byte{'x'}, 3*flushSize)
+	ctx, cancel := context.WithCancel(context.Background())
+	w := cancelWriter{cancel: cancel}
+	n, err := FprintfContext(ctx, &w, buf, "%0x1000s*3")
+	if err != context.Canceled || n != flushSize || w.Len() != flushSize {
+		t.Logf("unexpected %d %v %d", n, err, w.Len())
+		t.Fail()
+	}
+	// The separator buffered after the cancelling write is dropped.
+	ctx, cancel = context.WithCancel(context.Background())
+	w = cancelWriter{cancel: cancel}
+	n, err = FprintfContext(ctx, &w, buf, "%0x1000s; %0x1000s")
+	if err != context.Canceled || n != flushSize || w.Len() != flushSize {
+		t.Logf("unexpected %d %v %d", n, err, w.Len())
+		t.Fail()
+	}
+	var b bytes.Buffer
+	n, err = FprintfContext(context.Background(), &b, []byte{1}, "%1d")
+	if err != nil || n != 1 || b.String() != "1" {
+		t.Logf("unexpected %d %v %q", n, err, b.String())
+		t.Fail()
+	}
+}
+
+func TestOnField(t *testing.T) {
+	var fields []string
+	d := NewDumper()
+	d.OnField = func(verb byte, start, end int, text string) {
+		fields = append(fields, string(verb)+":"+strconv.Itoa(start)+"-"+strconv.Itoa(end)+"="+text)
+	}
+	d.Sprintf([]byte{0, 1, 'h', 'i', 2, 3, 0xf0}, "len %2d str %2s %1d*2 %4(bits)", map[int64]string{})
+	expect := []string{"d:0-2=1", "s:2-4=hi", "d:4-5=2", "d:5-6=3", "(:6-7=15"}
+	if strings.Join(fields, " ") != strings.Join(expect, " ") {
+		t.Logf("unexpected fields %q", fields)
+		t.Fail()
+	}
+	fields = fields[:0]
+	buf := append([]byte{0x88, 0x27}, bytes.Repeat([]byte{'a'}, 5000)...)
+	d.Fprintf(io.Discard, buf, "x%.0(lendelim)", "%1s...")
+	if len(fields) != 1 || len(fields[0]) != len("(:0-5002=")+5000+4999*len(d.sep()) {
+		t.Logf("nested flush: unexpected fields %d", len(fields))
+		t.Fail()
+	}
+}
+
+func TestHexString(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0xde, 0xad, 0xbe, 0xef}, "%h", "de:ad:be:ef"},
+		{[]byte{0xde, 0xad, 0xbe, 0xef}, "%2h %1x", "de:ad be"},
+		{[]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, "%10h", "00:01:02:03:04:05:06:07:08:09"},
+		{[]byte{0x0f, 0xf0, 0xaa}, "%.1h", "0f:f0"},
+		{[]byte{0xff}, "%h", "ff"},
+		{[]byte{}, "%h", ""},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+	d := NewDumper()
+	d.HexSeparator = "-"
+	res := d.Sprintf([]byte{0x01, 0x23, 0x45}, "%h")
+	if res != "01-23-45" {
+		t.Logf("hex separator: expected %q, res %q", "01-23-45", res)
+		t.Fail()
+	}
+}
+
+func TestIndent(t *testing.T) {
+	d := NewDumper()
+	d.Indent = "  "
+	buf := []byte("0123456789abcdefXY")
+	dump := hex.Dump(buf)
+	lines := strings.SplitAfter(dump, "\n")
+	var tests = []struct {
+		fmt    string
+		expect string
+	}{
+		{"%p", "  " + lines[0] + "  " + lines[1]},
+		{"data:\n%p", "data:\n  " + lines[0] + "  " + lines[1]},
+		{"data: %p", "data: " + lines[0] + "  " + lines[1]},
+		{"%16s %p", "0123456789abcdef " + hex.Dump(buf[16:])},
+		{"%s", string(buf)},
+	}
+	for _, tt := range tests {
+		res := d.Sprintf(buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+	res := d.Sprintf([]byte{0xa5, 0x0f}, "%2.8(grid)")
+	if res != "  #.#..#.#\n  ....####\n" {
+		t.Logf("grid: unexpected %q", res)
+		t.Fail()
+	}
+}
+
+func TestMaxOutput(t *testing.T) {
+	d := NewDumper()
+	d.MaxOutput = 8
+	buf := []byte("0123456789abcdefXY")
+	var tests = []struct {
+		fmt    string
+		expect string
+	}{
+		{"%4s", "0123"},
+		{"%8s", "01234567"},
+		{"%9s", "01234567" + OutputLimit},
+		{"%1d...", "48, 49, " + OutputLimit},
+		{"%p", hex.Dump(buf)[:8] + OutputLimit},
+		{"header: %2s", "header: " + OutputLimit},
+		{"header:  %2s", "header: " + OutputLimit},
+	}
+	for _, tt := range tests {
+		res := d.Sprintf(buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+	var b bytes.Buffer
+	d.MaxOutput = 5000
+	_, err := d.Fprintf(&b, make([]byte, 2000), "%p")
+	if e, ok := err.(*OutputLimitError); !ok || e.Max != 5000 || b.Len() != 5000+len(OutputLimit) {
+		t.Logf("Fprintf: unexpected error %v, %d bytes", err, b.Len())
+		t.Fail()
+	}
+	// A large value is not rendered in full before it is cut.
+	d.MaxOutput = 100
+	large := make([]byte, 10<<20)
+	for _, f := range []string{"%p", "%s", "%q", "%C", "%h", "%a", "%l"} {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		res := d.Sprintf(large, f)
+		runtime.ReadMemStats(&after)
+		if len(res) != 100+len(OutputLimit) || after.TotalAlloc-before.TotalAlloc > 1<<20 {
+			t.Logf("format %q: %d bytes of output, allocated %d", f, len(res), after.TotalAlloc-before.TotalAlloc)
+			t.Fail()
+		}
+		small := []byte("日本\x00\x01abc\"\xffdef0123456789")
+		small = bytes.Repeat(small, 10)
+		if res, full := d.Sprintf(small, f), Sprintf(small, f); res != full[:100]+OutputLimit {
+			t.Logf("format %q: unexpected %q", f, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestPeek(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0, 0, 1, 0}, "%=4d (%4x)", "256 (100)"},
+		{[]byte{0, 1, 2}, "%=-2d %2d %1d", "256 1 2"},
+		{[]byte{0x41, 0x42}, "%=s %2x", "AB 4142"},
+		{[]byte{0xf0}, "%.4d %=.2d %.2d %.2d", "15 0 0 0"},
+		{[]byte{1, 2}, "%=1d*3 %1d", "1, 1, 1 1"},
+		{[]byte{1}, "%=2d", "%%EOF%"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestWidthArg(t *testing.T) {
+	var tests = []struct {
+		fmt    string
+		expect string
+	}{
+		{"%*s", "ab"},
+		{"%*s %*d", "ab 99"},
+		{"%_*.3s|", "ab |"},
+		{"%1.2e %*s", "one bc"},
+		{"%*s %*s %*s", "ab c %%BADARG%4"},
+		{"%*", "%%NOVERB%%*"},
+	}
+	for _, tt := range tests {
+		res := Sprintf([]byte("abc"), tt.fmt, 2, 1, map[int64]string{0x61: "one"}, "x")
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+	m := map[int64]string{0x61: "one"}
+	if res := Sprintf([]byte("abc"), "%1.0e %*s", m, 2); res != "one bc" {
+		t.Logf("width after map: unexpected %q", res)
+		t.Fail()
+	}
+	if res := Sprintf([]byte("abc"), "%*s", -1); res != "%%BADARG%0" {
+		t.Logf("negative width: unexpected %q", res)
+		t.Fail()
+	}
+	if err := Validate("%*.2s"); err != nil {
+		t.Logf("validate: unexpected %v", err)
+		t.Fail()
+	}
+}
+
+func TestPooledDumper(t *testing.T) {
+	d := getDumper()
+	d.Sprintf([]byte{1, 2, 3}, "%1d %K%1d")
+	putDumper(d)
+	d = getDumper()
+	if d.input != nil || d.ii != 0 || d.mark != 0 || d.w != nil {
+		t.Logf("pooled dumper keeps state: %+v", d)
+		t.Fail()
+	}
+	putDumper(d)
+	done := make(chan bool)
+	for i := 0; i < 4; i++ {
+		go func(i int) {
+			for j := 0; j < 100; j++ {
+				b := []byte{byte(i), byte(j)}
+				if res := Sprintf(b, "%1d %1d"); res != strconv.Itoa(i)+" "+strconv.Itoa(j) {
+					t.Logf("concurrent Sprintf: unexpected %q", res)
+					t.Fail()
+				}
+			}
+			done <- true
+		}(i)
+	}
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+}
+
+func TestReset(t *testing.T) {
+	d := NewDumper()
+	d.Separator = " "
+	d.Sprintf(make([]byte, 256), "%256p")
+	c := d.buf.Cap()
+	d.Reset()
+	if d.Separator != " " || d.buf.Len() != 0 || d.buf.Cap() != c || d.input != nil {
+		t.Logf("reset: unexpected %q %d %d", d.Separator, d.buf.Len(), d.buf.Cap())
+		t.Fail()
+	}
+}
+
+func BenchmarkSprintfParallel(b *testing.B) {
+	buf := []byte{0, 0, 1, 0, 'h', 'e', 'l', 'l', 'o', 0xc0, 0xa8, 0, 1}
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			Sprintf(buf, "len=%4d str=%5s ip=%I")
+		}
+	})
+}
+
+func BenchmarkSprintfNoPool(b *testing.B) {
+	buf := []byte{0, 0, 1, 0, 'h', 'e', 'l', 'l', 'o', 0xc0, 0xa8, 0, 1}
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			var d Dumper
+			d.Sprintf(buf, "len=%4d str=%5s ip=%I")
+		}
+	})
+}
+
+func TestOffset(t *testing.T) {
+	buf := make([]byte, 300)
+	var tests = []struct {
+		fmt    string
+		expect string
+	}{
+		{"%O: %1d", "0: 0"},
+		{"%2z%O", "2"},
+		{"%255z%#O %O", "ff 255"},
+		{"%255z%#8O", "000000ff"},
+		{"%4O %=2d %O", "0000 0 0"},
+		{"%.3d%O", "00"},
+		{"%1d %O*2", "0 1, 1"},
+		{"%0x12C@%O", "300"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestLabel(t *testing.T) {
+	var tests = []struct {
+		fmt    string
+		expect string
+	}{
+		{"%L=%1d", "len=1"},
+		{"%.1L=%1d %L=%1d", "type=1 len=2"},
+		{"%L*2 %1d", "len, len 1"},
+		{"%.2L", "%%BADARG%2"},
+		{"%.3L", "%%BADARG%3"},
+	}
+	for _, tt := range tests {
+		res := Sprintf([]byte{1, 2}, tt.fmt, "len", "type", 5, 1.0)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+	if n, err := ConsumedLength("%L%2d"); n != 2 || err != nil {
+		t.Logf("ConsumedLength: unexpected %d, %v", n, err)
+		t.Fail()
+	}
+}
+
+func TestMissingVerb(t *testing.T) {
+	var tests = []struct {
+		fmt    string
+		expect string
+	}{
+		{"%", "%%NOVERB%%"},
+		{"%1d %", "1 %%NOVERB%%"},
+		{"%-", "%%NOVERB%%-"},
+		{"%#+", "%%NOVERB%%#+"},
+		{"%0", "%%NOVERB%%0"},
+		{"%04", "%%NOVERB%%04"},
+		{"%4", "%%NOVERB%%4"},
+		{"%0x1", "%%NOVERB%%0x1"},
+		{"%4.", "%%NOVERB%%4."},
+		{"%.2", "%%NOVERB%%.2"},
+		{"%1d%%", "1%"},
+		{"%(bits", "%%UNKOWN%(bits"},
+	}
+	for _, tt := range tests {
+		res := Sprintf([]byte{1, 2, 3, 4}, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestCompactDump(t *testing.T) {
+	buf := []byte("0123456789abcdefXY\x00")
+	var tests = []struct {
+		lineBytes int
+		fmt       string
+		expect    string
+	}{
+		{0, "%#p", "30 31 32 33 34 35 36 37 38 39 61 62 63 64 65 66 |0123456789abcdef|\n" +
+			"58 59 00                                        |XY.|\n"},
+		{4, "%#6p", "30 31 32 33 |0123|\n34 35       |45|\n"},
+		{4, "%#.15p", "30 31 32 33 |0123|\n"},
+		{4, "%#0p", ""},
+		{0, "%3p", hex.Dump(buf[:3])},
+	}
+	d := NewDumper()
+	for _, tt := range tests {
+		d.LineBytes = tt.lineBytes
+		res := d.Sprintf(buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+	d.LineBytes = 2
+	d.Indent = "> "
+	if res := d.Sprintf(buf, "%#4p"); res != "> 30 31 |01|\n> 32 33 |23|\n" {
+		t.Logf("indented: unexpected %q", res)
+		t.Fail()
+	}
+}
+
+func TestRestAtEnd(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{1}, "%1d|%p|%#p|%q|%s|%a|%l|%h|%B|%Z", "1|||\"\"|||[]byte{}|||"},
+		{[]byte{0xf0}, "%.4d|%s|%q|%Z", "15||\"\"|"},
+		{[]byte{0xf0}, "%.4d|%.1s", "15|%%EOF%"},
+		{[]byte{1, 2}, "%1@%s|%2@%s|%3@%s", "\x02||%%EOF%"},
+		{[]byte{1, 2}, "%s|%s|%.1s", "\x01\x02||%%EOF%"},
+		{[]byte{1, 2}, "%=s|%s", "\x01\x02|\x01\x02"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestAlign(t *testing.T) {
+	buf := []byte{1, 0, 0, 0, 2, 0, 0, 0, 3}
+	var tests = []struct {
+		fmt    string
+		expect string
+	}{
+		{"%1d %4|%1d", "1 2"},
+		{"%4|%1d", "1"},
+		{"%4z%4|%1d", "2"},
+		{"%1d %8|%1d", "1 3"},
+		{"%.3d %2|%O", "0 2"},
+		{"%|%1d %1|%O %0|%O", "1 1 1"},
+		{"%1d*5 %4|%O", "1, 0, 0, 0, 2 8"},
+		{"%1d %16|%1d", "1 %%EOF%"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestDecodeInt(t *testing.T) {
+	var tests = []struct {
+		buf          []byte
+		width        int
+		littleEndian bool
+		expect       int64
+		err          string
+	}{
+		{[]byte{0x12, 0x34}, 2, false, 0x1234, ""},
+		{[]byte{0x12, 0x34}, 2, true, 0x3412, ""},
+		{[]byte{0x12, 0x34, 0x56}, 1, false, 0x12, ""},
+		{[]byte{1, 2, 3, 4, 5, 6, 7, 8}, 8, true, 0x0807060504030201, ""},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, 8, false, -1, ""},
+		{nil, 0, false, 0, ""},
+		{[]byte{0x12}, 2, false, 0, "bytefmt: %2d at offset 0 exceeds input"},
+		{[]byte{0x12}, 2, true, 0, "bytefmt: %-2d at offset 0 exceeds input"},
+		{make([]byte, 9), 9, false, 0, "bytefmt: %9d width 9 exceeds 8 bytes"},
+	}
+	for _, tt := range tests {
+		x, err := DecodeInt(tt.buf, tt.width, tt.littleEndian)
+		var e string
+		if err != nil {
+			e = err.Error()
+		}
+		if x != tt.expect || e != tt.err {
+			t.Logf("DecodeInt(%x, %d, %v): expected %#x %q, got %#x %q", tt.buf, tt.width, tt.littleEndian, tt.expect, tt.err, x, e)
+			t.Fail()
+		}
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	var d Dumper
+	var _ io.WriterTo = &d
+	d.Sprintf([]byte{0, 42, 'h', 'i'}, "%2d %s")
+	var b bytes.Buffer
+	n, err := d.WriteTo(&b)
+	if b.String() != "42 hi" || n != 5 || err != nil {
+		t.Logf("WriteTo: unexpected %q, %d, %v", b.String(), n, err)
+		t.Fail()
+	}
+	n, err = d.WriteTo(&b)
+	if n != 0 || err != nil {
+		t.Logf("WriteTo: drained output written again: %d, %v", n, err)
+		t.Fail()
+	}
+	d.Appendf(nil, []byte{7}, "%1d")
+	full := errors.New("full")
+	if _, err = d.WriteTo(&chunkWriter{chunks: []int{0}, fail: full}); err != full {
+		t.Logf("WriteTo: expected write error, got %v", err)
+		t.Fail()
+	}
+}
+
+func BenchmarkWriteTo(b *testing.B) {
+	buf := []byte{0, 0, 1, 0, 'h', 'e', 'l', 'l', 'o', 0xc0, 0xa8, 0, 1}
+	var d Dumper
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		d.Appendf(nil, buf, "len=%4d str=%5s ip=%I")
+		d.WriteTo(io.Discard)
+	}
+}
+
+func TestEnumRaw(t *testing.T) {
+	var enumValues = map[int64]string{
+		1: "One",
+		2: "Two",
+	}
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x02}, "%#1.0e", "2 (Two)"},
+		{[]byte{0x02, 0x00}, "%#-2.0e", "2 (Two)"},
+		{[]byte{0x07}, "%#1.0e", "7"},
+		{[]byte{0x00}, "%#1.0e", "0"},
+		{[]byte{0x01, 0x02}, "%#1.0e %1.0e", "1 (One) Two"},
+		{[]byte{0x03}, "%#1e", "3"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt, enumValues)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+	d := NewDumper()
+	d.ZeroLabel = "none"
+	if res := d.Sprintf([]byte{0, 0}, "%#1.0e %1.0e", enumValues); res != "0 none" {
+		t.Logf("ZeroLabel: unexpected %q", res)
+		t.Fail()
+	}
+}
+
+func TestAppendfTo(t *testing.T) {
+	dst := make([]byte, 0, 64)
+	dst = append(dst, "rec: "...)
+	out, consumed, err := AppendfTo(dst, []byte{0, 42, 7, 0xff, 9}, "%2d %1v %1x", "unused")
+	if string(out) != "rec: 42 7 ff" || consumed != 4 || err != nil || &out[0] != &dst[0] {
+		t.Logf("AppendfTo: unexpected %q, %d, %v", out, consumed, err)
+		t.Fail()
+	}
+	out, consumed, err = AppendfTo(nil, []byte{1}, "%1d %2d")
+	if _, ok := err.(*TruncatedError); string(out) != "1 %%EOF%" || consumed != 1 || !ok {
+		t.Logf("AppendfTo truncated: unexpected %q, %d, %v", out, consumed, err)
+		t.Fail()
+	}
+	var d Dumper
+	small := []byte("ab")
+	out, _, _ = d.AppendfTo(small[:1:1], []byte("xyz"), "%s")
+	if string(out) != "axyz" || string(small) != "ab" {
+		t.Logf("AppendfTo grown: unexpected %q, dst %q", out, small)
+		t.Fail()
+	}
+	if res := d.Sprintf([]byte{5}, "%1d"); res != "5" || string(out) != "axyz" {
+		t.Logf("AppendfTo: Dumper reused %q, unexpected %q", out, res)
+		t.Fail()
+	}
+	d.MaxOutput = 5
+	out, _, err = d.AppendfTo([]byte("0123"), []byte("abcdefgh"), "%s")
+	if _, ok := err.(*OutputLimitError); string(out) != "0123abcde"+OutputLimit || !ok {
+		t.Logf("AppendfTo MaxOutput: unexpected %q, %v", out, err)
+		t.Fail()
+	}
+	d.MaxOutput = 0
+	var fields []string
+	d.OnField = func(verb byte, start, end int, text string) {
+		fields = append(fields, text)
+	}
+	d.IgnorePartial = true
+	out, _, _ = d.AppendfTo([]byte("rec: "), []byte{1, 2, 3, 4, 5}, "%2x...")
+	if string(out) != "rec: 102, 304" || len(fields) != 2 || fields[0] != "102" || fields[1] != "304" {
+		t.Logf("AppendfTo OnField: unexpected %q, %q", out, fields)
+		t.Fail()
+	}
+}
+
+func BenchmarkAppendfTo(b *testing.B) {
+	buf := []byte{0, 0, 1, 0, 0x12, 0x34, 0x56, 0x78, 0xff, 0, 1}
+	dst := make([]byte, 0, 64)
+	var d Dumper
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		dst, _, _ = d.AppendfTo(dst[:0], buf, "len=%4d id=%4x flags=%1v count=%2d")
+	}
+}
@@ -0,0 +1,105 @@
+package bytefmt
+
+import (
+	"hash/adler32"
+	"hash/crc32"
+	"math"
+	"strconv"
+)
+
+// checksum computes the checksum named alg over b. digits is the number
+// of hex digits of the result, ok is false for an unknown algorithm.
+// The supported algorithms are crc16 (CRC-16/ARC), crc32 (IEEE), crc32c
+// (Castagnoli) and adler32.
+func checksum(alg string, b []byte) (sum uint64, digits int, ok bool) {
+	switch alg {
+	case "crc16":
+		var crc uint16
+		for _, c := range b {
+			crc ^= uint16(c)
+			for i := 0; i < 8; i++ {
+				if crc&1 != 0 {
+					crc = crc>>1 ^ 0xa001
+				} else {
+					crc >>= 1
+				}
+			}
+		}
+		return uint64(crc), 4, true
+	case "crc32":
+		return uint64(crc32.ChecksumIEEE(b)), 8, true
+	case "crc32c":
+		return uint64(crc32.Checksum(b, crc32.MakeTable(crc32.Castagnoli))), 8, true
+	case "adler32":
+		return uint64(adler32.Checksum(b)), 8, true
+	}
+	return 0, 0, false
+}
+
+// fmtChecksum prints the checksum of the input from the %K mark up to
+// the current byte. The algorithm is the string argument selected by
+// prec, crc32 by default.
+func (d *Dumper) fmtChecksum(a []interface{}) {
+	alg := "crc32"
+	if d.precValid {
+		alg = d.argString(a, d.prec)
+	}
+	sum, digits, ok := checksum(alg, d.marked())
+	if !ok {
+		d.badValue(alg)
+		return
+	}
+	d.writeHex(sum, digits)
+}
+
+// marked returns the input from the %K mark up to the current byte.
+func (d *Dumper) marked() []byte {
+	d.alignByte()
+	start := d.mark
+	if start > d.ii {
+		start = d.ii
+	}
+	return d.input[start:d.ii]
+}
+
+// fmtByteSum prints the 8 bit sum of the input from the %K mark up to
+// the current byte, or with the # flag its XOR. The parameter is the
+// base, 10 by default, and 16 prints 0x prefixed hex as %k does.
+func (d *Dumper) fmtByteSum(a []interface{}) {
+	var sum byte
+	for _, c := range d.marked() {
+		if d.altFlag {
+			sum ^= c
+		} else {
+			sum += c
+		}
+	}
+	switch base := d.paramInt(0, 10); {
+	case base == 16:
+		d.writeHex(uint64(sum), 2)
+	case base >= 2 && base <= 36:
+		d.buf.WriteString(strconv.FormatUint(uint64(sum), base))
+	default:
+		d.badValue(d.params[0])
+	}
+}
+
+// fmtEntropy prints the Shannon entropy of width (default the rest)
+// bytes in bits per byte, from 0 for a single repeated byte up to 8 for
+// random data. The parameter is the number of decimals (default 2).
+func (d *Dumper) fmtEntropy(a []interface{}) {
+	d.restWidth()
+	b := d.fetchBytes(d.width)
+	var count [256]int
+	for _, c := range b {
+		count[c]++
+	}
+	var h float64
+	for _, n := range count {
+		if n > 0 {
+			p := float64(n) / float64(len(b))
+			h -= p * math.Log2(p)
+		}
+	}
+	d.buf.WriteString(strconv.FormatFloat(h, 'f', d.paramInt(0, 2), 64))
+}
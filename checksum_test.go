@@ -0,0 +1,101 @@
+package bytefmt
+
+import (
+	"testing"
+)
+
+func TestChecksum(t *testing.T) {
+	var tests = []struct {
+		alg    string
+		expect uint64
+	}{
+		{"crc16", 0xbb3d},
+		{"crc32", 0xcbf43926},
+		{"crc32c", 0xe3069283},
+		{"adler32", 0x091e01de},
+	}
+	for _, tt := range tests {
+		res, _, ok := checksum(tt.alg, []byte("123456789"))
+		if !ok || res != tt.expect {
+			t.Logf("checksum %s: expected %#x, res %#x", tt.alg, tt.expect, res)
+			t.Fail()
+		}
+	}
+	if _, _, ok := checksum("md5", nil); ok {
+		t.Logf("checksum md5: expected unknown algorithm")
+		t.Fail()
+	}
+}
+
+func TestChecksumVerb(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte("123456789"), "%9z%k", "0xcbf43926"},
+		{[]byte("123456789"), "%9z%.0k", "0xbb3d"},
+		{[]byte("xx123456789"), "%2s %K%9z%.1k", "xx 0x091e01de"},
+		{[]byte("xx123456789"), "%2s%K%s %.2k", "xx123456789 0xe3069283"},
+		{[]byte("ab"), "%2z%K%k", "0x00000000"},
+		{[]byte("ab"), "%2z%.3k", "%%BADVALUE%md5"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt, "crc16", "adler32", "crc32c", "md5")
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestByteSum(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{1, 2, 3}, "%3z%(bytesum)", "6"},
+		{[]byte{0x80, 0x90, 0x7f}, "%3z%(bytesum)", "143"},
+		{[]byte{1, 2, 3}, "%3z%#(bytesum)", "0"},
+		{[]byte{0xf0, 0x0f, 0x01}, "%3z%#(bytesum:16)", "0xfe"},
+		{[]byte{9, 1, 2, 3}, "%1d %K%3z%(bytesum:16)", "9 0x06"},
+		{[]byte{5, 6}, "%2z%(bytesum:2)", "1011"},
+		{[]byte{5, 6}, "%2z%(bytesum:1)", "%%BADVALUE%1"},
+		{[]byte{5, 6}, "%(bytesum)", "0"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestEntropy(t *testing.T) {
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{7, 7, 7, 7}, "%(entropy)", "0.00"},
+		{[]byte{0, 1, 0, 1}, "%(entropy)", "1.00"},
+		{[]byte{0, 1, 2, 3, 9}, "%4(entropy) %1d", "2.00 9"},
+		{[]byte{0, 0, 0, 1}, "%(entropy:3)", "0.811"},
+		{all, "%(entropy:0)", "8"},
+		{nil, "%(entropy)", "0.00"},
+		{[]byte{1}, "%2(entropy)", "%%EOF%"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package bytefmt
+
+import (
+	"image/color"
+	"strconv"
+)
+
+// fmtPalette prints the color for an index of width (default 1) bytes
+// in the color.Palette or []color.Color argument selected by prec as
+// #RRGGBB.
+func (d *Dumper) fmtPalette(a []interface{}) {
+	if !d.widthValid {
+		d.width = 1
+	}
+	x := d.fetchInt()
+	var p []color.Color
+	switch v := d.arg(a, d.prec).(type) {
+	case color.Palette:
+		p = v
+	case []color.Color:
+		p = v
+	default:
+		d.badArg(d.prec)
+	}
+	if x < 0 || x >= int64(len(p)) {
+		d.badValue(strconv.FormatInt(x, 10))
+		return
+	}
+	r, g, b, _ := p[x].RGBA()
+	d.buf.WriteRune('#')
+	for _, c := range []uint32{r, g, b} {
+		d.buf.WriteByte("0123456789ABCDEF"[c>>12])
+		d.buf.WriteByte("0123456789ABCDEF"[c>>8&0xf])
+	}
+}
@@ -0,0 +1,25 @@
+package bytefmt
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestPalette(t *testing.T) {
+	var pal = color.Palette{
+		color.RGBA{0x00, 0x00, 0x00, 0xff},
+		color.RGBA{0xff, 0x80, 0x0a, 0xff},
+	}
+	res := Sprintf([]byte{0x01, 0x00, 0x05}, "%.0(palette) %.0(palette) %.0(palette)", pal)
+	expected := "#FF800A #000000 %%BADVALUE%5"
+	if res != expected {
+		t.Logf("palette expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf([]byte{0x00, 0x01}, "%2.0(palette)", []color.Color(pal))
+	expected = "#FF800A"
+	if res != expected {
+		t.Logf("palette expected %q, res %q", expected, res)
+		t.Fail()
+	}
+}
@@ -0,0 +1,260 @@
+package bytefmt
+
+import (
+	"math"
+	"strconv"
+)
+
+// fetchFixed consumes a signed fixed point number of d.width bytes with
+// frac fraction bits.
+func (d *Dumper) fetchFixed(frac int) float64 {
+	return math.Ldexp(float64(signExtend(d.fetchInt(), d.width)), -frac)
+}
+
+// fmtQ prints a fixed point number of width (default 4) bytes with prec
+// fraction bits, by default half of its bits. It is unsigned unless the
+// + flag is given.
+func (d *Dumper) fmtQ() {
+	if !d.widthValid {
+		d.width = 4
+	}
+	frac := d.width * 4
+	if d.precValid {
+		frac = d.prec
+	}
+	v := d.fetchSigned()
+	x := float64(v)
+	if !d.signed {
+		x = float64(uint64(v))
+	}
+	d.buf.WriteString(strconv.FormatFloat(math.Ldexp(x, -frac), 'g', -1, 64))
+}
+
+// fmtFixRow prints a row of prec signed fixed point numbers of width
+// bytes each. The parameter gives the number of fraction bits, by
+// default half of the bits of an element.
+func (d *Dumper) fmtFixRow(a []interface{}) {
+	if !d.widthValid {
+		d.width = 4
+	}
+	frac := d.paramInt(0, d.width*4)
+	d.buf.WriteRune('[')
+	for n := 0; n < d.prec; n++ {
+		if n > 0 {
+			d.buf.WriteString(d.sep())
+		}
+		d.buf.WriteString(strconv.FormatFloat(d.fetchFixed(frac), 'g', -1, 64))
+	}
+	d.buf.WriteRune(']')
+}
+
+// fmtMP4Matrix prints a QuickTime/MP4 transformation matrix of nine 4
+// byte elements. The last column is in 2.30 fixed point, all other
+// elements are in 16.16.
+func (d *Dumper) fmtMP4Matrix(a []interface{}) {
+	d.width = 4
+	d.buf.WriteRune('[')
+	for row := 0; row < 3; row++ {
+		if row > 0 {
+			d.buf.WriteString(d.sep())
+		}
+		d.buf.WriteRune('[')
+		for col := 0; col < 3; col++ {
+			if col > 0 {
+				d.buf.WriteString(d.sep())
+			}
+			frac := 16
+			if col == 2 {
+				frac = 30
+			}
+			d.buf.WriteString(strconv.FormatFloat(d.fetchFixed(frac), 'g', -1, 64))
+		}
+		d.buf.WriteRune(']')
+	}
+	d.buf.WriteRune(']')
+}
+
+// fmtFixSplit prints a signed fixed point number stored as a width
+// (default 2) byte two's complement integer part followed by a prec
+// (default 1) byte unsigned fraction part.
+func (d *Dumper) fmtFixSplit(a []interface{}) {
+	if !d.widthValid {
+		d.width = 2
+	}
+	if !d.precValid {
+		d.prec = 1
+	}
+	x := float64(signExtend(d.fetchInt(), d.width))
+	d.width = d.prec
+	x += math.Ldexp(float64(d.fetchInt()), -8*d.prec)
+	d.buf.WriteString(strconv.FormatFloat(x, 'g', -1, 64))
+}
+
+// fmtGain prints a signed fixed point gain in dB of width (default 2)
+// bytes, with the parameter giving the fraction bits (default 8). The #
+// flag converts the gain to a linear amplitude.
+func (d *Dumper) fmtGain(a []interface{}) {
+	if !d.widthValid {
+		d.width = 2
+	}
+	db := d.fetchFixed(d.paramInt(0, 8))
+	if d.altFlag {
+		d.writeFloat(math.Pow(10, db/20))
+		return
+	}
+	d.writeFloat(db)
+	d.buf.WriteString(" dB")
+}
+
+// fmtBounded prints a signed fixed point number of width (default 2)
+// bytes, with the parameter giving the fraction bits (default 8), and
+// marks it if it is outside the float64 minimum and maximum arguments
+// at prec and prec+1. The # flag clamps the value to the bounds.
+func (d *Dumper) fmtBounded(a []interface{}) {
+	if !d.widthValid {
+		d.width = 2
+	}
+	x := d.fetchFixed(d.paramInt(0, 8))
+	min, max := d.argFloat(a, d.prec), d.argFloat(a, d.prec+1)
+	mark := ""
+	switch {
+	case x < min:
+		mark = " <MIN"
+		if d.altFlag {
+			x = min
+		}
+	case x > max:
+		mark = " >MAX"
+		if d.altFlag {
+			x = max
+		}
+	}
+	d.buf.WriteString(strconv.FormatFloat(x, 'g', -1, 64) + mark)
+}
+
+// fmtNorm prints a normalized integer of width (default 2) bytes as a
+// float, either UNORM in 0..1 or with the # flag SNORM in -1..1.
+func (d *Dumper) fmtNorm(a []interface{}) {
+	if !d.widthValid {
+		d.width = 2
+	}
+	x := d.fetchInt()
+	bits := 8 * d.width
+	if !d.altFlag {
+		d.writeFloat(float64(x) / float64(uint64(1)<<uint(bits)-1))
+		return
+	}
+	f := float64(signExtend(x, d.width)) / float64(uint64(1)<<uint(bits-1)-1)
+	if f < -1 {
+		f = -1
+	}
+	d.writeFloat(f)
+}
+
+// fmtQAuto prints a signed fixed point number preceded by a Q format
+// descriptor byte holding the integer bits, including the sign, in the
+// high nibble and the fraction bits in the low nibble. The value takes
+// as many bytes as needed for all the bits.
+func (d *Dumper) fmtQAuto(a []interface{}) {
+	q := d.fetchBytes(1)[0]
+	m, n := int(q>>4), int(q&0xf)
+	d.width = (m + n + 7) / 8
+	d.writeFloat(d.fetchFixed(n))
+}
+
+// fmtDelta prints a signed fixed point difference of width (default 2)
+// bytes with an explicit sign, followed by the string unit argument
+// selected by prec. The parameters are the fraction bits (default 8)
+// and the number of decimals (default as many as needed).
+func (d *Dumper) fmtDelta(a []interface{}) {
+	if !d.widthValid {
+		d.width = 2
+	}
+	x := d.fetchFixed(d.paramInt(0, 8))
+	s := strconv.FormatFloat(x, 'f', d.paramInt(1, -1), 64)
+	if s[0] != '-' {
+		d.buf.WriteRune('+')
+	}
+	d.buf.WriteString(s)
+	if d.precValid {
+		d.buf.WriteString(" " + d.argString(a, d.prec))
+	}
+}
+
+// fmtInterp prints a signed fixed point number of width (default 2)
+// bytes, with the parameter giving the fraction bits (default 0),
+// linearly interpolated in the [][2]float64 table of ascending input
+// and output pairs selected by prec. Values outside of the table are
+// clamped to its ends.
+func (d *Dumper) fmtInterp(a []interface{}) {
+	if !d.widthValid {
+		d.width = 2
+	}
+	x := d.fetchFixed(d.paramInt(0, 0))
+	table := d.argTable(a, d.prec)
+	var y float64
+	switch {
+	case len(table) == 0:
+		y = x
+	case x <= table[0][0]:
+		y = table[0][1]
+	case x >= table[len(table)-1][0]:
+		y = table[len(table)-1][1]
+	default:
+		i := 1
+		for table[i][0] < x {
+			i++
+		}
+		p, q := table[i-1], table[i]
+		y = p[1] + (x-p[0])*(q[1]-p[1])/(q[0]-p[0])
+	}
+	d.buf.WriteString(strconv.FormatFloat(y, 'g', -1, 64))
+}
+
+// fmtADC prints an unsigned ADC reading of width (default 2) bytes as a
+// voltage relative to the float64 reference voltage argument selected by
+// prec. The parameters are the ADC resolution in bits (default all bits
+// of the reading, higher bits are ignored) and the number of decimals.
+func (d *Dumper) fmtADC(a []interface{}) {
+	if !d.widthValid {
+		d.width = 2
+	}
+	bits := uint(d.paramInt(0, 8*d.width))
+	full := uint64(1)<<bits - 1
+	x := uint64(d.fetchInt()) & full
+	v := float64(x) / float64(full) * d.argFloat(a, d.prec)
+	d.buf.WriteString(strconv.FormatFloat(v, 'f', d.paramInt(1, -1), 64))
+}
+
+// fmtHexFix prints a signed fixed point number of width (default 2)
+// bytes with prec (default 8) fraction bits in hex, placing the radix
+// point at the nibble boundary, e.g. 0x1.8 for 1.5.
+func (d *Dumper) fmtHexFix(a []interface{}) {
+	if !d.widthValid {
+		d.width = 2
+	}
+	if !d.precValid {
+		d.prec = 8
+	}
+	x := signExtend(d.fetchInt(), d.width)
+	if x < 0 {
+		d.buf.WriteRune('-')
+		x = -x
+	}
+	u := uint64(x)
+	frac := uint(d.prec)
+	d.buf.WriteString("0x" + strconv.FormatUint(u>>frac, 16))
+	digits := int(frac+3) / 4
+	f := (u & (1<<frac - 1)) << (uint(4*digits) - frac)
+	if f == 0 {
+		return
+	}
+	h := strconv.FormatUint(f, 16)
+	for len(h) < digits {
+		h = "0" + h
+	}
+	for h[len(h)-1] == '0' {
+		h = h[:len(h)-1]
+	}
+	d.buf.WriteString("." + h)
+}
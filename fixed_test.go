@@ -0,0 +1,266 @@
+package bytefmt
+
+import (
+	"testing"
+)
+
+func TestFixRow(t *testing.T) {
+	buf := []byte{0x01, 0x80, 0xff, 0x80, 0x00, 0x00}
+	res := Sprintf(buf, "%2.3(fixrow)")
+	expected := "[1.5, -0.5, 0]"
+	if res != expected {
+		t.Logf("fixrow expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf(buf, "%2.3(fixrow:4)")
+	expected = "[24, -8, 0]"
+	if res != expected {
+		t.Logf("fixrow expected %q, res %q", expected, res)
+		t.Fail()
+	}
+}
+
+func TestMP4Matrix(t *testing.T) {
+	buf := []byte{
+		0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x40, 0x00, 0x00, 0x00,
+	}
+	res := Sprintf(buf, "%(mp4matrix)")
+	expected := "[[1, 0, 0], [0, 1, 0], [0, 0, 1]]"
+	if res != expected {
+		t.Logf("mp4matrix expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	buf[4], buf[8], buf[12] = 0xff, 0xc0, 0x00
+	res = Sprintf(buf, "%(mp4matrix)")
+	expected = "[[1, -256, -1], [0, 1, 0], [0, 0, 1]]"
+	if res != expected {
+		t.Logf("mp4matrix expected %q, res %q", expected, res)
+		t.Fail()
+	}
+}
+
+func TestFixSplit(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x00, 0x01, 0x80}, "%(fixsplit)", "1.5"},
+		{[]byte{0xff, 0xfe, 0x80}, "%2.1(fixsplit)", "-1.5"},
+		{[]byte{0x01, 0x00, 0x40}, "%-2.1(fixsplit)", "1.25"},
+		{[]byte{0xff, 0x40, 0x00}, "%1.2(fixsplit)", "-0.75"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestGain(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x00, 0x00}, "%(gain)", "0 dB"},
+		{[]byte{0x00, 0x00}, "%#(gain)", "1"},
+		{[]byte{0xfa, 0x00}, "%(gain)", "-6 dB"},
+		{[]byte{0xfa, 0x00}, "%#.2(gain)", "0.50"},
+		{[]byte{0xa0}, "%1.1(gain:4)", "-6.0 dB"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestBounded(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x01, 0x80}, "%.0(bounded)", "1.5"},
+		{[]byte{0xf6, 0x00}, "%.0(bounded)", "-10 <MIN"},
+		{[]byte{0xf6, 0x00}, "%#.0(bounded)", "-5 <MIN"},
+		{[]byte{0x64, 0x00}, "%.0(bounded)", "100 >MAX"},
+		{[]byte{0x64, 0x00}, "%#.0(bounded)", "50 >MAX"},
+		{[]byte{0x64}, "%1.0(bounded:0)", "100 >MAX"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt, -5.0, 50.0)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestNorm(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0xff, 0xff}, "%(norm)", "1"},
+		{[]byte{0x00, 0x00}, "%(norm)", "0"},
+		{[]byte{0x80, 0x00}, "%.3(norm)", "0.500"},
+		{[]byte{0x7f, 0xff}, "%#(norm)", "1"},
+		{[]byte{0x80, 0x01}, "%#(norm)", "-1"},
+		{[]byte{0x80, 0x00}, "%#(norm)", "-1"},
+		{[]byte{0x00, 0xc0}, "%#-.2(norm)", "-0.50"},
+		{[]byte{0xff}, "%1(norm)", "1"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestQAuto(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x88, 0x01, 0x80}, "%(qauto)", "1.5"},
+		{[]byte{0x88, 0xff, 0x40}, "%(qauto)", "-0.75"},
+		{[]byte{0x88, 0x80, 0x01}, "%-(qauto)", "1.5"},
+		{[]byte{0x4c, 0xe0, 0x00}, "%(qauto)", "-2"},
+		{[]byte{0x44, 0x28}, "%.2(qauto)", "2.50"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestDelta(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x01, 0x40}, "%.0(delta:8,2)", "+1.25 V"},
+		{[]byte{0xff, 0x80}, "%.0(delta:8,2)", "-0.50 V"},
+		{[]byte{0xff, 0x80}, "%.0(delta)", "-0.5 V"},
+		{[]byte{0x00, 0x00}, "%(delta)", "+0"},
+		{[]byte{0xec}, "%1.0(delta:2)", "-5 V"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt, "V")
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestInterp(t *testing.T) {
+	var table = [][2]float64{
+		{0, -40},
+		{100, 10},
+		{200, 30},
+	}
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x00, 0x32}, "%.0(interp)", "-15"},
+		{[]byte{0x00, 0x96}, "%.0(interp)", "20"},
+		{[]byte{0x00, 0x64}, "%.0(interp)", "10"},
+		{[]byte{0xff, 0xf6}, "%.0(interp)", "-40"},
+		{[]byte{0x01, 0x00}, "%.0(interp)", "30"},
+		{[]byte{0x02, 0x80}, "%.0(interp:4)", "-20"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt, table)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestADC(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x0f, 0xff}, "%.0(adc:12)", "3.3"},
+		{[]byte{0xff, 0xff}, "%.0(adc:12)", "3.3"},
+		{[]byte{0x08, 0x00}, "%.0(adc:12,3)", "1.650"},
+		{[]byte{0x00, 0x00}, "%.0(adc)", "0"},
+		{[]byte{0xff}, "%1.0(adc)", "3.3"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt, 3.3)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestHexFix(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x01, 0x80}, "%(hexfix)", "0x1.8"},
+		{[]byte{0xfe, 0x80}, "%(hexfix)", "-0x1.8"},
+		{[]byte{0x12, 0x34}, "%.12(hexfix)", "0x1.234"},
+		{[]byte{0x00, 0x0f}, "%.12(hexfix)", "0x0.00f"},
+		{[]byte{0x68}, "%1.6(hexfix)", "0x1.a"},
+		{[]byte{0x2a, 0x00}, "%(hexfix)", "0x2a"},
+		{[]byte{0x00, 0x01, 0x40, 0x00}, "%4.16(hexfix)", "0x1.4"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestQ(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x01, 0x80}, "%2.8j", "1.5"},
+		{[]byte{0x80, 0x01}, "%-2.8j", "1.5"},
+		{[]byte{0x01, 0x80}, "%2j", "1.5"},
+		{[]byte{0xff, 0x80}, "%2.8j", "255.5"},
+		{[]byte{0xff, 0x80}, "%+2.8j", "-0.5"},
+		{[]byte{0x00, 0x01, 0x40, 0x00}, "%j", "1.25"},
+		{[]byte{0x60, 0x00}, "%2.15j", "0.75"},
+		{[]byte{0x05}, "%1.0j", "5"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
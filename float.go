@@ -0,0 +1,221 @@
+package bytefmt
+
+import (
+	"math"
+	"strconv"
+)
+
+// float16 converts the IEEE 754 binary16 value h to a float64.
+func float16(h uint16) float64 {
+	return minifloat(uint64(h), 5, 10)
+}
+
+// halfFloat converts x to the nearest IEEE 754 binary16 value, the
+// inverse of float16. ok is false if x is too large for one.
+func halfFloat(x float64) (h uint16, ok bool) {
+	sign := uint16(math.Float64bits(x)>>48) & 0x8000
+	switch {
+	case math.IsNaN(x):
+		return sign | 0x7e00, true
+	case math.IsInf(x, 0):
+		return sign | 0x7c00, true
+	}
+	a := math.Abs(x)
+	if a < 0x1p-14 {
+		// Subnormal, rounding up to 0x400 gives the smallest normal.
+		return sign | uint16(math.RoundToEven(a*0x1p24)), true
+	}
+	frac, exp := math.Frexp(a)
+	m := math.RoundToEven((2*frac - 1) * 1024)
+	e := exp - 1 + 15
+	if m == 1024 {
+		m = 0
+		e++
+	}
+	if e >= 31 {
+		return 0, false
+	}
+	return sign | uint16(e)<<10 | uint16(m), true
+}
+
+// extFloat converts x to an x87 extended precision float with sign and
+// exponent se and mantissa m, the inverse of float80.
+func extFloat(x float64) (se uint16, m uint64) {
+	se = uint16(math.Float64bits(x)>>48) & 0x8000
+	switch {
+	case math.IsNaN(x):
+		return se | 0x7fff, 0xc000000000000000
+	case math.IsInf(x, 0):
+		return se | 0x7fff, 1 << 63
+	case x == 0:
+		return se, 0
+	}
+	frac, exp := math.Frexp(math.Abs(x))
+	return se | uint16(exp+16382), uint64(frac * 0x1p64)
+}
+
+// minifloat converts x, an IEEE 754 style float with a sign bit, e
+// exponent bits and m mantissa bits, to a float64.
+func minifloat(x uint64, e, m int) float64 {
+	sign := 1.0
+	if x>>uint(e+m)&1 != 0 {
+		sign = -1
+	}
+	exp := int(x>>uint(m)) & (1<<uint(e) - 1)
+	frac := float64(x & (1<<uint(m) - 1))
+	bias := 1<<uint(e-1) - 1
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(frac, 1-bias-m)
+	case 1<<uint(e) - 1:
+		if frac != 0 {
+			return math.NaN()
+		}
+		return math.Inf(int(sign))
+	}
+	return sign * math.Ldexp(float64(uint64(1)<<uint(m))+frac, exp-bias-m)
+}
+
+// float80 converts the x87 extended precision float with sign and
+// exponent se and mantissa m, which has an explicit integer bit, to a
+// float64.
+func float80(se uint16, m uint64) float64 {
+	sign := 1.0
+	if se&0x8000 != 0 {
+		sign = -1
+	}
+	exp := int(se & 0x7fff)
+	switch exp {
+	case 0:
+		exp = 1
+	case 0x7fff:
+		if m<<1 != 0 {
+			return math.NaN()
+		}
+		return math.Inf(int(sign))
+	}
+	return sign * math.Ldexp(float64(m), exp-16383-63)
+}
+
+// writeFloat prints x with prec decimals, or as short as possible if
+// no precision was given.
+func (d *Dumper) writeFloat(x float64) {
+	if d.precValid {
+		d.buf.WriteString(strconv.FormatFloat(x, 'f', d.prec, 64))
+	} else {
+		d.buf.WriteString(strconv.FormatFloat(x, 'g', -1, 64))
+	}
+}
+
+// fmtFloat prints a float as fetched by fetchFloat in the notation of
+// the verb c: %f in fixed and %E in scientific notation with prec
+// decimals, %g with prec significant digits. Without a prec, %f and %g
+// print the shortest representation, %E its scientific form.
+func (d *Dumper) fmtFloat(c byte) {
+	x, ok := d.fetchFloat()
+	if !ok {
+		return
+	}
+	if c == 'f' {
+		d.writeFloat(x)
+		return
+	}
+	prec := -1
+	if d.precValid {
+		prec = d.prec
+	}
+	if c == 'E' {
+		c = 'e'
+	}
+	d.buf.WriteString(strconv.FormatFloat(x, c, prec, 64))
+}
+
+// fetchFloat consumes a 4 (default), 8 or 2 byte IEEE 754 float, or a
+// 10 byte x87 extended precision one. For other widths it reports
+// BadWidth, skips width bytes and returns false.
+func (d *Dumper) fetchFloat() (float64, bool) {
+	if !d.widthValid {
+		d.width = 4
+	}
+	switch d.width {
+	case 2:
+		return float16(uint16(d.fetchInt())), true
+	case 4:
+		return float64(math.Float32frombits(uint32(d.fetchInt()))), true
+	case 8:
+		return math.Float64frombits(uint64(d.fetchInt())), true
+	case 10:
+		b := d.fetchBytes(10)
+		if d.intel {
+			return float80(uint16(decodeInt(b[8:], true)), uint64(decodeInt(b[:8], true))), true
+		}
+		return float80(uint16(decodeInt(b[:2], false)), uint64(decodeInt(b[2:], false))), true
+	}
+	d.badWidth(d.width)
+	d.fetchBytes(d.width)
+	return 0, false
+}
+
+// fmtRGBA16F prints a pixel of four half precision float channels.
+func (d *Dumper) fmtRGBA16F(a []interface{}) {
+	d.width = 2
+	d.buf.WriteRune('(')
+	for n := 0; n < 4; n++ {
+		if n > 0 {
+			d.buf.WriteString(d.sep())
+		}
+		d.writeFloat(float16(uint16(d.fetchInt())))
+	}
+	d.buf.WriteRune(')')
+}
+
+// fmtBitFloat prints an IEEE 754 float read from the bit cursor, so it
+// need not start on a byte boundary. The width is 4 (default) for a
+// float32 or 8 for a float64. Other widths are flagged with BadWidth,
+// skipping width bytes.
+func (d *Dumper) fmtBitFloat(a []interface{}) {
+	if !d.widthValid {
+		d.width = 4
+	}
+	n := d.width
+	if n != 4 && n != 8 {
+		d.badWidth(n)
+		for i := 0; i < n; i++ {
+			d.fetchBits(8)
+		}
+		return
+	}
+	var x uint64
+	for i := 0; i < n; i++ {
+		b := d.fetchBits(8)
+		if d.intel {
+			x |= b << uint(8*i)
+		} else {
+			x = x<<8 | b
+		}
+	}
+	if n == 8 {
+		d.writeFloat(math.Float64frombits(x))
+	} else {
+		d.writeFloat(float64(math.Float32frombits(uint32(x))))
+	}
+}
+
+// fmtMiniFloat prints a float with a sign bit, width (default 5)
+// exponent bits and prec (default 10) mantissa bits read from the bit
+// cursor. No exponent bits, or more than 64 bits in all, are flagged
+// with BadWidth.
+func (d *Dumper) fmtMiniFloat(a []interface{}) {
+	if !d.widthValid {
+		d.width = 5
+	}
+	if !d.precValid {
+		d.prec = 10
+	}
+	if d.width == 0 || 1+d.width+d.prec > 64 {
+		d.badWidth(d.width)
+		return
+	}
+	x := d.fetchBits(1 + d.width + d.prec)
+	d.buf.WriteString(strconv.FormatFloat(minifloat(x, d.width, d.prec), 'g', -1, 64))
+}
@@ -0,0 +1,185 @@
+package bytefmt
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFloat16(t *testing.T) {
+	var tests = []struct {
+		h      uint16
+		expect float64
+	}{
+		{0x0000, 0},
+		{0x3c00, 1},
+		{0xc000, -2},
+		{0x3555, 0.333251953125},
+		{0x7bff, 65504},
+		{0x0001, math.Ldexp(1, -24)},
+		{0x03ff, math.Ldexp(1023, -24)},
+		{0x7c00, math.Inf(1)},
+		{0xfc00, math.Inf(-1)},
+	}
+	for _, tt := range tests {
+		res := float16(tt.h)
+		if res != tt.expect {
+			t.Logf("float16 %#04x: expected %v, res %v", tt.h, tt.expect, res)
+			t.Fail()
+		}
+		if h, ok := halfFloat(tt.expect); !ok || h != tt.h {
+			t.Logf("halfFloat %v: expected %#04x, res %#04x", tt.expect, tt.h, h)
+			t.Fail()
+		}
+	}
+	if h, ok := halfFloat(1 + 1.0/2048); !ok || h != 0x3c00 {
+		t.Logf("halfFloat: expected a tie rounded to even, res %#04x", h)
+		t.Fail()
+	}
+	if h, ok := halfFloat(65519); !ok || h != 0x7bff {
+		t.Logf("halfFloat 65519: expected 0x7bff, res %#04x", h)
+		t.Fail()
+	}
+	if _, ok := halfFloat(65520); ok {
+		t.Logf("halfFloat 65520: expected an overflow")
+		t.Fail()
+	}
+	if res := float16(0x7e00); !math.IsNaN(res) {
+		t.Logf("float16 0x7e00: expected NaN, res %v", res)
+		t.Fail()
+	}
+}
+
+func TestRGBA16F(t *testing.T) {
+	buf := []byte{0x3c, 0x00, 0x3c, 0x00, 0x3c, 0x00, 0x3c, 0x00}
+	res := Sprintf(buf, "%(rgba16f)")
+	expected := "(1, 1, 1, 1)"
+	if res != expected {
+		t.Logf("rgba16f expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	buf = []byte{0x00, 0x40, 0x00, 0x38, 0x00, 0x00, 0x00, 0x3c}
+	res = Sprintf(buf, "%-.2(rgba16f)")
+	expected = "(2.00, 0.50, 0.00, 1.00)"
+	if res != expected {
+		t.Logf("rgba16f expected %q, res %q", expected, res)
+		t.Fail()
+	}
+}
+
+func TestBitFloat(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0xa7, 0xf8, 0x00, 0x00, 0x00}, "%3(bits) %(bitfloat)", "5 1.5"},
+		{[]byte{0xa0, 0x00, 0x18, 0x07, 0xe0}, "%3(bits) %-(bitfloat)", "5 1.5"},
+		{[]byte{0x3f, 0xf8, 0, 0, 0, 0, 0, 0}, "%8(bitfloat)", "1.5"},
+		{[]byte{0x40, 0x49, 0x0f, 0xdb}, "%.4(bitfloat)", "3.1416"},
+		{[]byte{0x3c, 0x00, 0x42}, "%2(bitfloat) %1x", BadWidth + "2 42"},
+		{[]byte{0xa7, 0xf8, 0x00, 0x42}, "%3(bits) %2(bitfloat) %(bits)", "5 " + BadWidth + "2 0"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestMiniFloat(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x38}, "%4.3(minifloat)", "1"},
+		{[]byte{0xbc}, "%4.3(minifloat)", "-1.5"},
+		{[]byte{0x01}, "%4.3(minifloat)", "0.001953125"},
+		{[]byte{0x78}, "%4.3(minifloat)", "+Inf"},
+		{[]byte{0x3c, 0x00}, "%(minifloat)", "1"},
+		{[]byte{0x7f, 0x80, 0x00, 0x00}, "%8.23(minifloat)", "+Inf"},
+		{[]byte{0xbf, 0xc0, 0x00, 0x00}, "%8.23(minifloat)", "-1.5"},
+		// 1 + 3 + 2 bits each: 0 011 00, 1 100 01
+		{[]byte{0x33, 0x10}, "%3.2(minifloat) %3.2(minifloat)", "1 -2.5"},
+		{[]byte{0x38}, "%0.3(minifloat) %1x", BadWidth + "0 38"},
+		{[]byte{0x38}, "%11.53(minifloat) %1x", BadWidth + "11 38"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestFloat(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x3f, 0xc0, 0x00, 0x00}, "%f", "1.5"},
+		{[]byte{0x00, 0x00, 0xc0, 0x3f}, "%-4f", "1.5"},
+		{[]byte{0x40, 0x49, 0x0f, 0xdb}, "%4.3f", "3.142"},
+		{[]byte{0x40, 0x09, 0x21, 0xfb, 0x54, 0x44, 0x2d, 0x18}, "%8f", "3.141592653589793"},
+		{[]byte{0x18, 0x2d, 0x44, 0x54, 0xfb, 0x21, 0x09, 0x40}, "%-8.2f", "3.14"},
+		{[]byte{0xff, 0x80, 0x00, 0x00}, "%f", "-Inf"},
+		{[]byte{0x3f, 0xc0, 0x00, 0x2a}, "%3f%1x", "%%BADWIDTH%32a"},
+		{[]byte{0x3c, 0x00}, "%2f", "1"},
+		{[]byte{0x00, 0xc0}, "%-2f", "-2"},
+		{[]byte{0x42, 0x48}, "%2.2f", "3.14"},
+		{[]byte{0x7b, 0xff}, "%2f", "65504"},
+		{[]byte{0x00, 0x01}, "%2f", "5.960464477539063e-08"},
+		{[]byte{0x7c, 0x00}, "%2f", "+Inf"},
+		{[]byte{0xfc, 0x00}, "%2f", "-Inf"},
+		{[]byte{0x7e, 0x00}, "%2f", "NaN"},
+		{[]byte{0x3f, 0xc0, 0x00, 0x00}, "%E", "1.5e+00"},
+		{[]byte{0x4c, 0x51, 0xb9, 0xa0}, "%E", "5.4978176e+07"},
+		{[]byte{0x4c, 0x51, 0xb9, 0xa0}, "%.2E", "5.50e+07"},
+		{[]byte{0x4c, 0x51, 0xb9, 0xa0}, "%.2f", "54978176.00"},
+		{[]byte{0x4c, 0x51, 0xb9, 0xa0}, "%.2g", "5.5e+07"},
+		{[]byte{0x4c, 0x51, 0xb9, 0xa0}, "%g", "5.4978176e+07"},
+		{[]byte{0x40, 0x49, 0x0f, 0xdb}, "%.3g", "3.14"},
+		{[]byte{0x00, 0x3c}, "%-2E", "1e+00"},
+		{[]byte{0x3f, 0xc0, 0x00}, "%3E", "%%BADWIDTH%3"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestFloat80(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x40, 0x0e, 0xac, 0x44, 0, 0, 0, 0, 0, 0}, "%10f", "44100"},
+		{[]byte{0, 0, 0, 0, 0, 0, 0x80, 0xbb, 0x0e, 0x40}, "%-10f", "48000"},
+		{[]byte{0x3f, 0xff, 0x80, 0, 0, 0, 0, 0, 0, 0}, "%10f", "1"},
+		{[]byte{0xbf, 0xfe, 0xc0, 0, 0, 0, 0, 0, 0, 0}, "%10f", "-0.75"},
+		{[]byte{0x40, 0x00, 0xc9, 0x0f, 0xda, 0xa2, 0x21, 0x68, 0xc2, 0x35}, "%10.5f", "3.14159"},
+		{[]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, "%10f", "0"},
+		{[]byte{0x00, 0x00, 0x40, 0, 0, 0, 0, 0, 0, 0}, "%10f", "0"},
+		{[]byte{0x7f, 0xff, 0x80, 0, 0, 0, 0, 0, 0, 0}, "%10f", "+Inf"},
+		{[]byte{0xff, 0xff, 0x80, 0, 0, 0, 0, 0, 0, 0}, "%10f", "-Inf"},
+		{[]byte{0x7f, 0xff, 0xc0, 0, 0, 0, 0, 0, 0, 0}, "%10f", "NaN"},
+		{[]byte{0x7f, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, "%10f", "+Inf"},
+		{[]byte{0x40, 0x0e, 0xac, 0x44, 0, 0, 0, 0, 0, 0}, "%10E", "4.41e+04"},
+		{[]byte{0x40, 0x0e, 0xac, 0x44}, "%10f", "%%EOF%"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package bytefmt
+
+import (
+	"math"
+	"math/bits"
+	"strconv"
+)
+
+// fmtReserved prints an integer in hex and flags any of the bits in the
+// int64 mask argument selected by prec that are set.
+func (d *Dumper) fmtReserved(a []interface{}) {
+	if !d.widthValid {
+		d.width = 4
+	}
+	x := d.fetchInt()
+	d.buf.WriteString(strconv.FormatInt(x, 16))
+	if !d.precValid {
+		return
+	}
+	r := x & d.argInt64(a, d.prec)
+	if r == 0 {
+		return
+	}
+	d.buf.WriteString(" RESERVED(")
+	var needOr = false
+	for bit := 0; r != 0; bit++ {
+		if r&1 != 0 {
+			if needOr {
+				d.buf.WriteString(d.flagSep())
+			}
+			d.buf.WriteString("bit" + strconv.Itoa(bit))
+			needOr = true
+		}
+		r >>= 1
+	}
+	d.buf.WriteRune(')')
+}
+
+// fmtParity prints the low 7 bits of a byte in decimal and flags a
+// parity error against bit 7. Even parity is checked unless the # flag
+// selects odd parity.
+func (d *Dumper) fmtParity(a []interface{}) {
+	b := d.fetchBytes(1)[0]
+	d.buf.WriteString(strconv.Itoa(int(b & 0x7f)))
+	odd := bits.OnesCount8(b)&1 != 0
+	if odd != d.altFlag {
+		d.buf.WriteString(" PARITY")
+	}
+}
+
+// fmtSignHex prints a two's complement integer of width (default 4)
+// bytes in decimal followed by its raw encoding in hex.
+func (d *Dumper) fmtSignHex(a []interface{}) {
+	if !d.widthValid {
+		d.width = 4
+	}
+	x := d.fetchInt()
+	d.buf.WriteString(strconv.FormatInt(signExtend(x, d.width), 10))
+	d.buf.WriteString(" (")
+	d.writeHex(uint64(x), 2*d.width)
+	d.buf.WriteRune(')')
+}
+
+// fmtPercent prints an int as a percentage of a maximum, as fetched by
+// fetchRatio. The parameter is the number of decimals, 1 by default.
+func (d *Dumper) fmtPercent(a []interface{}) {
+	r, ok := d.fetchRatio(a)
+	if !ok {
+		return
+	}
+	d.buf.WriteString(strconv.FormatFloat(100*r, 'f', d.paramInt(0, 1), 64) + "%")
+}
+
+// barBlocks are the block characters of a bar filled by 0 to 8 eighths.
+var barBlocks = []rune(" ▏▎▍▌▋▊▉█")
+
+// fmtBar prints an int as a bar of block characters, filled to the
+// fraction of a maximum fetched by fetchRatio and clamped to 0..1. The
+// length of the bar is the int argument after the maximum, 10 without a
+// prec.
+func (d *Dumper) fmtBar(a []interface{}) {
+	r, ok := d.fetchRatio(a)
+	if !ok {
+		return
+	}
+	n := 10
+	if d.precValid {
+		n = d.argInt(a, d.prec+1)
+	}
+	r = math.Max(0, math.Min(r, 1))
+	eighths := int(math.Round(r * float64(8*n)))
+	for i := 0; i < n; i++ {
+		e := eighths - 8*i
+		switch {
+		case e > 8:
+			e = 8
+		case e < 0:
+			e = 0
+		}
+		d.buf.WriteRune(barBlocks[e])
+	}
+}
+
+// fetchRatio consumes an int of width (default DefaultIntWidth) bytes and
+// returns it as a fraction of the float64 argument selected by prec, by
+// default of the largest unsigned value of the width. A zero maximum is
+// flagged with BadValue and ok is false.
+func (d *Dumper) fetchRatio(a []interface{}) (r float64, ok bool) {
+	d.intWidth()
+	x := float64(d.fetchSigned())
+	max := float64(uint64(1)<<uint(8*d.width) - 1)
+	if d.precValid {
+		max = d.argFloat(a, d.prec)
+	}
+	if max == 0 {
+		d.badValue("")
+		return 0, false
+	}
+	return x / max, true
+}
+
+// writeDecimal prints the decimal number s, with its digits grouped by
+// thousands if the , flag was given.
+func (d *Dumper) writeDecimal(s string) {
+	if !d.group {
+		d.buf.WriteString(s)
+		return
+	}
+	sep := d.DigitSeparator
+	if sep == "" {
+		sep = ","
+	}
+	if s[0] == '-' {
+		d.buf.WriteByte('-')
+		s = s[1:]
+	}
+	for i := 0; i < len(s); i++ {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			d.buf.WriteString(sep)
+		}
+		d.buf.WriteByte(s[i])
+	}
+}
+
+// writeDecimalInt is like writeDecimal for the int x, formatted without
+// allocating.
+func (d *Dumper) writeDecimalInt(x int64) {
+	b := strconv.AppendInt(d.num[:0], x, 10)
+	if !d.group {
+		d.buf.Write(b)
+		return
+	}
+	d.writeDecimal(string(b))
+}
+
+// writeHex prints x as 0x prefixed hex, zero padded to digits digits.
+func (d *Dumper) writeHex(x uint64, digits int) {
+	d.buf.WriteString("0x")
+	h := strconv.FormatUint(x, 16)
+	for n := len(h); n < digits; n++ {
+		d.buf.WriteRune('0')
+	}
+	d.buf.WriteString(h)
+}
+
+// writeInt prints x in base 2, 8 or 16. With the 0 flag, x is printed
+// zero padded to the number of digits of d.width bytes, unsigned unless
+// the + flag was given.
+func (d *Dumper) writeInt(x int64, base int) {
+	if !d.zeroPad {
+		d.buf.Write(strconv.AppendInt(d.num[:0], x, base))
+		return
+	}
+	bits := 1
+	for 1<<uint(bits) < base {
+		bits++
+	}
+	if d.signed && x < 0 {
+		d.buf.WriteRune('-')
+		x = -x
+	}
+	s := strconv.FormatUint(uint64(x), base)
+	for n := len(s); n < (8*d.width+bits-1)/bits; n++ {
+		d.buf.WriteRune('0')
+	}
+	d.buf.WriteString(s)
+}
+
+// fetchVarint consumes a LEB128 varint. ok is false if it is longer
+// than 10 bytes or overflows 64 bits, its bytes are still consumed up
+// to the last one.
+func (d *Dumper) fetchVarint() (x uint64, ok bool) {
+	ok = true
+	for i := uint(0); ; i++ {
+		b := d.fetchBytes(1)[0]
+		if i > 9 || i == 9 && b > 1 {
+			ok = false
+		} else {
+			x |= uint64(b&0x7f) << (7 * i)
+		}
+		if b < 0x80 {
+			return x, ok
+		}
+	}
+}
+
+// fmtVarint prints an unsigned LEB128 varint.
+func (d *Dumper) fmtVarint() {
+	x, ok := d.fetchVarint()
+	if !ok {
+		d.badValue("")
+	}
+	d.buf.WriteString(strconv.FormatUint(x, 10))
+}
+
+// fmtSize prints an unsigned int of width (default DefaultIntWidth)
+// bytes as a byte size with binary prefixes, e.g. 1.5 MiB, or with the
+// # flag decimal ones, e.g. 1.5 MB. prec is the number of decimals
+// (default 1), sizes below 1 KiB or 1 kB are printed in bytes.
+func (d *Dumper) fmtSize(a []interface{}) {
+	d.intWidth()
+	x := uint64(d.fetchInt())
+	unit, prefixes, suffix := 1024.0, "KMGTPE", "iB"
+	if d.altFlag {
+		unit, prefixes, suffix = 1000, "kMGTPE", "B"
+	}
+	if float64(x) < unit {
+		d.buf.WriteString(strconv.FormatUint(x, 10) + " B")
+		return
+	}
+	prec := 1
+	if d.precValid {
+		prec = d.prec
+	}
+	v, i := float64(x)/unit, 0
+	for v >= unit && i < len(prefixes)-1 {
+		v /= unit
+		i++
+	}
+	s := strconv.FormatFloat(v, 'f', prec, 64)
+	if r, _ := strconv.ParseFloat(s, 64); r >= unit && i < len(prefixes)-1 {
+		// Rounded up to a whole unit, e.g. 1023.96 KiB to 1024.0 KiB.
+		v /= unit
+		i++
+		s = strconv.FormatFloat(v, 'f', prec, 64)
+	}
+	d.buf.WriteString(s + " " + prefixes[i:i+1] + suffix)
+}
+
+// fetchVLQ reads a big endian variable-length quantity as used by MIDI,
+// seven bits per byte with the high bit set on all but the last. ok is
+// false if the value overflows an int64, its bytes are still consumed
+// up to the last one.
+func (d *Dumper) fetchVLQ() (x int64, ok bool) {
+	ok = true
+	for {
+		b := d.fetchBytes(1)[0]
+		if x>>56 != 0 {
+			ok = false
+		}
+		x = x<<7 | int64(b&0x7f)
+		if b < 0x80 {
+			return x, ok
+		}
+	}
+}
+
+// fmtVLQ prints a MIDI variable-length quantity in decimal, one that
+// overflows an int64 as BadValue alone.
+func (d *Dumper) fmtVLQ(a []interface{}) {
+	x, ok := d.fetchVLQ()
+	if !ok {
+		d.badValue("")
+		return
+	}
+	d.buf.WriteString(strconv.FormatInt(x, 10))
+}
+
+// fmtZigzag prints a zigzag encoded signed LEB128 varint.
+func (d *Dumper) fmtZigzag() {
+	x, ok := d.fetchVarint()
+	if !ok {
+		d.badValue("")
+	}
+	d.buf.WriteString(strconv.FormatInt(int64(x>>1)^-int64(x&1), 10))
+}
+
+// fmtSignMag prints a sign-magnitude int of width (default
+// DefaultIntWidth) bytes in decimal. The top bit is the sign, so a
+// negative zero prints as -0.
+func (d *Dumper) fmtSignMag(a []interface{}) {
+	d.intWidth()
+	x := uint64(d.fetchInt())
+	sign := uint64(1) << uint(8*d.width-1)
+	s := strconv.FormatUint(x&^sign, 10)
+	if x&sign != 0 {
+		s = "-" + s
+	}
+	d.writeDecimal(s)
+}
+
+// fmtExcess prints an excess-K int of width (default DefaultIntWidth)
+// bytes in decimal, that is the unsigned value less the bias K. The
+// parameter is K, which defaults to half the range of the width.
+func (d *Dumper) fmtExcess(a []interface{}) {
+	d.intWidth()
+	x := uint64(d.fetchInt())
+	bias := uint64(1) << uint(8*d.width-1)
+	if len(d.params) > 0 {
+		bias = uint64(d.paramInt(0, 0))
+	}
+	d.writeDecimal(strconv.FormatInt(int64(x-bias), 10))
+}
@@ -0,0 +1,433 @@
+package bytefmt
+
+import (
+	"testing"
+)
+
+func TestReserved(t *testing.T) {
+	var mask int64 = 0x62
+	res := Sprintf([]byte{0x81}, "%1.0(reserved)", mask)
+	expected := "81"
+	if res != expected {
+		t.Logf("reserved expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf([]byte{0xe3}, "%1.0(reserved)", mask)
+	expected = "e3 RESERVED(bit1|bit5|bit6)"
+	if res != expected {
+		t.Logf("reserved expected %q, res %q", expected, res)
+		t.Fail()
+	}
+}
+
+func TestParity(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x41}, "%(parity)", "65"},
+		{[]byte{0xc1}, "%(parity)", "65 PARITY"},
+		{[]byte{0xc1}, "%#(parity)", "65"},
+		{[]byte{0x41}, "%#(parity)", "65 PARITY"},
+		{[]byte{0x00}, "%(parity)", "0"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestSignHex(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0xff, 0xfe}, "%2(signhex)", "-2 (0xfffe)"},
+		{[]byte{0xfe, 0xff}, "%-2(signhex)", "-2 (0xfffe)"},
+		{[]byte{0x00, 0x00, 0x00, 0x2a}, "%(signhex)", "42 (0x0000002a)"},
+		{[]byte{0x80}, "%1(signhex)", "-128 (0x80)"},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, "%8(signhex)", "-1 (0xffffffffffffffff)"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestVarint(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x01}, "%w", "1"},
+		{[]byte{0xac, 0x02, 0x05}, "%w %1d", "300 5"},
+		{[]byte{0x7f, 0x80, 0x01}, "%w %w", "127 128"},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}, "%w", "18446744073709551615"},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x02}, "%w", "%%BADVALUE%9223372036854775807"},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01, 0x05}, "%w %1d", "%%BADVALUE%9223372036854775807 5"},
+		{[]byte{0x80, 0x80}, "%w", "%%EOF%"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestSize(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0, 0, 0x03, 0xff}, "%(size)", "1023 B"},
+		{[]byte{0, 0, 0x06, 0x00}, "%(size)", "1.5 KiB"},
+		{[]byte{0x00, 0x06}, "%-2.2(size)", "1.50 KiB"},
+		{[]byte{0, 0x18, 0, 0}, "%(size)", "1.5 MiB"},
+		{[]byte{0, 0x0f, 0xff, 0xff}, "%(size)", "1.0 MiB"},
+		{[]byte{0, 0x0f, 0x42, 0x3f}, "%#.2(size)", "1.00 MB"},
+		{[]byte{0, 0x0f, 0x42, 0x3f}, "%#(size)", "1.0 MB"},
+		{[]byte{0, 0x16, 0xe3, 0x60}, "%#(size)", "1.5 MB"},
+		{[]byte{0, 0, 0x03, 0xe7}, "%#(size)", "999 B"},
+		{[]byte{0x40, 0, 0, 0}, "%.0(size)", "1 GiB"},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, "%8(size)", "16.0 EiB"},
+		{[]byte{0, 0}, "%(size)", "%%EOF%"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestVLQ(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x00}, "%(vlq)", "0"},
+		{[]byte{0x7f, 0x05}, "%(vlq) %1d", "127 5"},
+		{[]byte{0x81, 0x00, 0xc0, 0x00}, "%(vlq) %(vlq)", "128 8192"},
+		{[]byte{0xff, 0xff, 0xff, 0x7f}, "%(vlq)", "268435455"},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f}, "%(vlq)", "9223372036854775807"},
+		{[]byte{0x81, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x00}, "%(vlq)", "%%BADVALUE%"},
+		{[]byte{0x81, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x00, 0x05}, "%(vlq) %1d", "%%BADVALUE% 5"},
+		{[]byte{0x81, 0x80}, "%(vlq)", "%%EOF%"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestZigzag(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x00}, "%W", "0"},
+		{[]byte{0x01, 0x02, 0x03}, "%W %W %W", "-1 1 -2"},
+		{[]byte{0xd7, 0x04}, "%W", "-300"},
+		{[]byte{0xfe, 0xff, 0xff, 0xff, 0x0f}, "%W", "2147483647"},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}, "%W", "-9223372036854775808"},
+		{[]byte{0x81}, "%W", "%%EOF%"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestDigitGroups(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0, 0, 0, 0, 0, 0x0f, 0x42, 0x40}, "%,8d", "1,000,000"},
+		{[]byte{0x00, 0x03, 0xe7}, "%,3d", "999"},
+		{[]byte{0x00, 0x03, 0xe8}, "%,3d", "1,000"},
+		{[]byte{0xff, 0xfe, 0x79, 0x60}, "%+,4d", "-100,000"},
+		{[]byte{0x40, 0x42, 0x0f, 0x00}, "%-,4d", "1,000,000"},
+		{[]byte{0xff, 0xff}, "%,.16d", "65,535"},
+		{[]byte{0x00}, "%,1d", "0"},
+		{[]byte{0x12, 0x34}, "%,2x", "1234"},
+		{[]byte{0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, "%,8d", "9,223,372,036,854,775,807"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+	d := NewDumper()
+	d.DigitSeparator = "."
+	if res := d.Sprintf([]byte{0x00, 0x0f, 0x42, 0x40}, "%,d"); res != "1.000.000" {
+		t.Logf("digit separator: unexpected %q", res)
+		t.Fail()
+	}
+}
+
+func TestIntelBases(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x01, 0x02, 0x03, 0x04}, "%4x", "1020304"},
+		{[]byte{0x01, 0x02, 0x03, 0x04}, "%-4x", "4030201"},
+		{[]byte{0x01, 0x02, 0x03, 0x04}, "%4d", "16909060"},
+		{[]byte{0x01, 0x02, 0x03, 0x04}, "%-4d", "67305985"},
+		{[]byte{0x01, 0x02, 0x03, 0x04}, "%4o", "100401404"},
+		{[]byte{0x01, 0x02, 0x03, 0x04}, "%-4o", "400601001"},
+		{[]byte{0x01, 0x80}, "%2b", "110000000"},
+		{[]byte{0x01, 0x80}, "%-2b", "1000000000000001"},
+		{[]byte{0x01, 0x00}, "%-02o", "000001"},
+		{[]byte{0x01, 0x00}, "%-02b", "0000000000000001"},
+		{[]byte{0x01, 0x00}, "%-2.0b", "(one)"},
+		{[]byte{0x01, 0x00}, "%->2b", "100000000"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt, map[int64]string{1: "one"})
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestDefaultIntWidth(t *testing.T) {
+	buf := []byte{0x01, 0x02, 0x03, 0x04, 'a', 'b'}
+	d := NewDumper()
+	var tests = []struct {
+		width  int
+		fmt    string
+		expect string
+	}{
+		{0, "%d", "16909060"},
+		{2, "%d %x", "258 304"},
+		{2, "%-d", "513"},
+		{2, "%1d %d %s", "1 515 \x04ab"},
+		{1, "%o %b %e %1.0e", "1 10 3 four"},
+		{2, "%q", "\"\\x01\\x02\\x03\\x04ab\""},
+		{2, "%f", "2.387939260590663e-38"},
+		{2, "%v", "1"},
+	}
+	for _, tt := range tests {
+		d.DefaultIntWidth = tt.width
+		res := d.Sprintf(buf, tt.fmt, map[int64]string{4: "four"})
+		if res != tt.expect {
+			t.Logf("width %d format %q: expected %q, res %q", tt.width, tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestSignedBases(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0xff, 0xff, 0xff, 0xd6}, "%x", "ffffffd6"},
+		{[]byte{0xff, 0xff, 0xff, 0xd6}, "%+x", "-2a"},
+		{[]byte{0xd6, 0xff, 0xff, 0xff}, "%-+x", "-2a"},
+		{[]byte{0x00, 0x00, 0x00, 0x2a}, "%+x", "2a"},
+		{[]byte{0xff, 0xd6}, "%+2o", "-52"},
+		{[]byte{0xfe}, "%+1b", "-10"},
+		{[]byte{0xff, 0xd6}, "%+02x", "-002a"},
+		{[]byte{0xff, 0xd6}, "%02x", "ffd6"},
+		{[]byte{0x80, 0, 0, 0, 0, 0, 0, 0}, "%+8x", "-8000000000000000"},
+		{[]byte{0xff}, "%+1.0b", "(one|0xfe)"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt, map[int64]string{1: "one"})
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestSignMag(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x05}, "%1(signmag)", "5"},
+		{[]byte{0x85}, "%1(signmag)", "-5"},
+		{[]byte{0x80}, "%1(signmag)", "-0"},
+		{[]byte{0xff}, "%1(signmag)", "-127"},
+		{[]byte{0x80, 0x02}, "%2(signmag)", "-2"},
+		{[]byte{0x02, 0x80}, "%-2(signmag)", "-2"},
+		{[]byte{0x80, 0x0f, 0x42, 0x40}, "%,(signmag)", "-1,000,000"},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, "%8(signmag)", "-9223372036854775807"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestExcess(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x80}, "%1(excess)", "0"},
+		{[]byte{0x00}, "%1(excess)", "-128"},
+		{[]byte{0xff}, "%1(excess)", "127"},
+		{[]byte{0x7e}, "%1(excess:127)", "-1"},
+		{[]byte{0x80, 0x05}, "%2(excess)", "5"},
+		{[]byte{0x05, 0x80}, "%-2(excess)", "5"},
+		{[]byte{0x00, 0x0a}, "%2(excess:-10)", "20"},
+		{[]byte{0x03, 0xe8}, "%2(excess:1000)", "0"},
+		{[]byte{0x00, 0x00, 0x00, 0x00}, "%,(excess)", "-2,147,483,648"},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, "%8(excess)", "9223372036854775807"},
+		{[]byte{0x00}, "%2(excess)", "%%EOF%"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestSlot(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0xde, 0xad, 0xbe, 0xef, 0, 0, 0x01, 0x00}, "%8.4d", "256"},
+		{[]byte{0x00, 0x01, 0, 0, 0xde, 0xad, 0xbe, 0xef}, "%-8.4d", "256"},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xfe}, "%+8.4d", "-2"},
+		{[]byte{0xfe, 0xff, 0xff, 0xff, 0x00, 0x00, 0x00, 0x00}, "%+-8.4d", "-2"},
+		{[]byte{0xaa, 0xaa, 0x12, 0x34, 0x05}, "%4.2x %1d", "1234 5"},
+		{[]byte{0x12, 0x34, 0xaa, 0xaa}, "%-4.2x", "3412"},
+		{[]byte{0xaa, 0xaa, 0x00, 0x0f}, "%04.2x", "000f"},
+		{[]byte{0x12, 0x34, 0x56}, "%2.2x %1d", "%%BADWIDTH%2 86"},
+		{[]byte{0x01, 0x02, 0x03, 0x04}, "%4.4x", "%%BADWIDTH%4"},
+		{[]byte{0x12, 0x34, 0x56}, "%2.0d %1d", "4660 86"},
+		{[]byte{0x12, 0x34, 0x56}, "%2.0x %1d", "1234 86"},
+		{[]byte{0x05}, "%1.0d", "5"},
+		{[]byte{0, 0, 0, 0, 0, 0, 0, 8, 9}, "%8.0d|%1d", "8|9"},
+		{[]byte{0, 0, 0, 0, 0, 0, 0, 8, 9}, "%8.1d|%1d", "8|9"},
+		{[]byte{0xf3, 0x07}, "%1.4d %1d", "%%BADWIDTH%4 7"},
+		{[]byte{0x12, 0x34, 0x56}, "%2.2d %1d", "%%BADWIDTH%2 86"},
+		{[]byte{0xf0, 0x01}, "%.4d %.4d %.8d", "15 0 1"},
+		{[]byte{0x12, 0x34, 0x56}, "%4.2d", "%%EOF%"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestPercent(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{50}, "%1.0(percent)", "25.0%"},
+		{[]byte{50}, "%1.0(percent:0)", "25%"},
+		{[]byte{0, 1}, "%2.0(percent:2)", "0.50%"},
+		{[]byte{0xff}, "%1(percent)", "100.0%"},
+		{[]byte{0x80, 0x00}, "%2(percent)", "50.0%"},
+		{[]byte{0xce}, "%+1.0(percent)", "-25.0%"},
+		{[]byte{250}, "%1.0(percent)", "125.0%"},
+		{[]byte{1}, "%1.1(percent)", "%%BADVALUE%"},
+		{[]byte{1}, "%1.2(percent)", "%%BADARG%2"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt, 200.0, 0.0)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestRadix(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0, 0, 0, 0xff}, "%4.16r", "ff"},
+		{[]byte{0, 0, 0x8c, 0xa0}, "%4.36r", "rs0"},
+		{[]byte{0x05, 0x00}, "%-2.2r", "101"},
+		{[]byte{0x1f}, "%1.32r", "v"},
+		{[]byte{0xff, 0xfe}, "%+2.3r", "-2"},
+		{[]byte{0, 0, 0, 42}, "%r", "42"},
+		{[]byte{42}, "%1.37r", "%%BADVALUE%37"},
+		{[]byte{42}, "%1.1r", "%%BADVALUE%1"},
+		{[]byte{42}, "%2.8r", "%%EOF%"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestBar(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{50}, "[%1.0(bar)]", "[██▌ ]"},
+		{[]byte{100}, "[%1.0(bar)]", "[████]"},
+		{[]byte{250}, "[%1.0(bar)]", "[████]"},
+		{[]byte{0}, "[%1.0(bar)]", "[    ]"},
+		{[]byte{0xf6}, "[%+1.0(bar)]", "[    ]"},
+		{[]byte{2}, "[%1.0(bar)]", "[▏   ]"},
+		{[]byte{0x80}, "[%1(bar)]", "[█████     ]"},
+		{[]byte{0, 70}, "[%2.0(bar)]", "[███▌]"},
+		{[]byte{1}, "%1.2(bar)", "%%BADVALUE%"},
+		{[]byte{1}, "%1.4(bar)", "%%BADARG%5"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt, 80.0, 4, 0.0, 1, 2.0)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
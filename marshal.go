@@ -0,0 +1,299 @@
+package bytefmt
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// Marshal encodes the struct v, or the one v points to, by the bytefmt
+// tags of its fields as read by Unmarshal. Int fields are written to
+// the width or bits of their format in its byte order, a negative value
+// needing the + flag. String and []byte fields are padded with zero
+// bytes or cut to the width of %s or %a, %Z appends a NUL. A %z tag
+// writes zero bytes, %@ pads with zero bytes up to its offset. Float
+// fields take %f of width 4, 8, 2 or 10 as Unmarshal does. A value
+// that does not fit its format returns a *FormatError.
+func Marshal(v interface{}) ([]byte, error) {
+	d := getDumper()
+	b, err := d.Marshal(v)
+	putDumper(d)
+	return b, err
+}
+
+// Marshal is like the package level Marshal, reusing d.
+func (d *Dumper) Marshal(v interface{}) (b []byte, err error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, &FormatError{Problem: "Marshal needs a struct or a pointer to one"}
+	}
+	d.Reset()
+	strict := d.Strict
+	d.Strict = true
+	defer func() {
+		d.Strict = strict
+		if e := recover(); e != nil {
+			b, err = nil, d.stopped(e)
+		}
+	}()
+	d.marshalStruct(rv)
+	return append([]byte(nil), d.buf.Bytes()...), nil
+}
+
+// marshalStruct encodes the tagged fields of the struct v.
+func (d *Dumper) marshalStruct(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup("bytefmt")
+		if !ok || tag == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		if tag == "" && f.Type.Kind() == reflect.Struct {
+			d.marshalStruct(fv)
+			continue
+		}
+		c, _, _, _, problem := d.spec.parse("%"+tag, 0)
+		if problem == "" && c == '(' {
+			problem = "named verbs are not supported"
+		}
+		if problem == "" && d.widthArg {
+			// There are no arguments to take the width from.
+			problem = "width arguments are not supported"
+		}
+		if problem != "" {
+			d.verb = "%" + tag
+			d.fail(f.Name + ": " + problem)
+		}
+		switch c {
+		case 'z':
+			if !d.widthValid {
+				d.width = 1
+			}
+			d.putZeros(d.width)
+			continue
+		case '@':
+			if d.width < d.consumed() {
+				d.fail(f.Name + ": offset " + strconv.Itoa(d.width) + " already passed")
+			}
+			d.putZeros(d.width - d.consumed())
+			continue
+		}
+		if !fv.CanInterface() || !d.marshalField(c, fv) {
+			d.fail("cannot encode " + f.Name + " of type " + f.Type.String())
+		}
+	}
+}
+
+// marshalField encodes v by the format with verb letter c. It returns
+// false if the type of v does not suit the format.
+func (d *Dumper) marshalField(c byte, v reflect.Value) bool {
+	switch c {
+	case 'd', 'v', 'x', 'o', 'b', 'w', 'W':
+		x, neg, ok := intValue(v)
+		if !ok {
+			return false
+		}
+		bits, slot, bad := d.intSlot(c)
+		switch {
+		case c == 'w' || c == 'W':
+			if c == 'W' {
+				if !neg && x>>63 != 0 {
+					d.badValue(strconv.FormatUint(x, 10))
+				}
+				x = x<<1 ^ uint64(int64(x)>>63)
+			} else if neg {
+				d.badValue(strconv.FormatInt(int64(x), 10))
+			}
+			d.putVarint(x)
+			return true
+		case bits:
+			d.checkFits(x, neg, d.prec)
+			d.putBits(x, d.prec)
+			return true
+		case bad:
+			d.badWidth(d.prec)
+		case c == 'v' && !d.widthValid:
+			d.width = 1
+		default:
+			d.intWidth()
+		}
+		if d.width > 8 {
+			panic(&WidthError{Verb: d.verb, Width: d.width})
+		}
+		if slot {
+			// The int is in the low prec bytes of the slot.
+			d.checkFits(x, neg, 8*d.prec)
+		} else {
+			d.checkFits(x, neg, 8*d.width)
+		}
+		d.putInt(x)
+		return true
+	case 'f':
+		if k := v.Kind(); k != reflect.Float32 && k != reflect.Float64 {
+			return false
+		}
+		if !d.widthValid {
+			d.width = 4
+		}
+		switch x := v.Float(); d.width {
+		case 2:
+			h, ok := halfFloat(x)
+			if !ok {
+				d.badValue(strconv.FormatFloat(x, 'g', -1, 64))
+			}
+			d.putInt(uint64(h))
+		case 4:
+			d.putInt(uint64(math.Float32bits(float32(x))))
+		case 8:
+			d.putInt(math.Float64bits(x))
+		case 10:
+			// The layout fetchFloat reads, mantissa first if intel.
+			se, m := extFloat(x)
+			if d.intel {
+				d.width = 8
+				d.putInt(m)
+				d.width = 2
+				d.putInt(uint64(se))
+			} else {
+				d.width = 2
+				d.putInt(uint64(se))
+				d.width = 8
+				d.putInt(m)
+			}
+		default:
+			d.badWidth(d.width)
+		}
+		return true
+	case 's', 'a', 'Z':
+		var b []byte
+		switch {
+		case v.Kind() == reflect.String:
+			b = []byte(v.String())
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+			b = v.Bytes()
+		default:
+			return false
+		}
+		if c == 'Z' {
+			d.putBytes(b)
+			d.putZeros(1)
+			return true
+		}
+		if d.widthValid && len(b) > d.width {
+			b = b[:d.width]
+		}
+		d.putBytes(b)
+		if d.widthValid {
+			d.putZeros(d.width - len(b))
+		}
+		return true
+	}
+	d.fail("unsupported verb " + string(c))
+	return false
+}
+
+// intValue returns the int or uint v as its two's complement bits x,
+// neg telling whether it is negative. ok is false if v is not an int.
+func intValue(v reflect.Value) (x uint64, neg bool, ok bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uint64(v.Int()), v.Int() < 0, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint(), false, true
+	}
+	return 0, false, false
+}
+
+// checkFits flags x, negative if neg, with BadValue if it does not fit
+// n bits, which hold a signed int with the + flag and an unsigned one
+// otherwise.
+func (d *Dumper) checkFits(x uint64, neg bool, n int) {
+	if d.signed {
+		n--
+	}
+	var ok bool
+	switch {
+	case n < 0:
+		ok = x == 0
+	case neg:
+		ok = d.signed && (n >= 63 || -x <= 1<<uint(n))
+	default:
+		ok = n >= 64 || x>>uint(n) == 0
+	}
+	if !ok {
+		if neg {
+			d.badValue(strconv.FormatInt(int64(x), 10))
+		} else {
+			d.badValue(strconv.FormatUint(x, 10))
+		}
+	}
+}
+
+// putBytes appends b to the output, starting at the next whole byte.
+func (d *Dumper) putBytes(b []byte) {
+	d.alignByte()
+	d.buf.Write(b)
+	d.ii += len(b)
+}
+
+// putZeros appends n zero bytes to the output.
+func (d *Dumper) putZeros(n int) {
+	d.alignByte()
+	for i := 0; i < n; i++ {
+		d.buf.WriteByte(0)
+	}
+	d.ii += n
+}
+
+// putInt appends the low d.width bytes of x in the byte order of the
+// format.
+func (d *Dumper) putInt(x uint64) {
+	b := d.num[:d.width]
+	for i := range b {
+		if d.intel {
+			b[i] = byte(x >> uint(8*i))
+		} else {
+			b[len(b)-1-i] = byte(x >> uint(8*i))
+		}
+	}
+	d.putBytes(b)
+}
+
+// putVarint appends x as an unsigned LEB128 varint.
+func (d *Dumper) putVarint(x uint64) {
+	d.alignByte()
+	for x >= 0x80 {
+		d.buf.WriteByte(byte(x) | 0x80)
+		d.ii++
+		x >>= 7
+	}
+	d.buf.WriteByte(byte(x))
+	d.ii++
+}
+
+// putBits appends the low n bits of x in the order fetchBits reads
+// them, filling the last byte of the output.
+func (d *Dumper) putBits(x uint64, n int) {
+	for i := 0; i < n; i++ {
+		if d.bit == 0 {
+			d.buf.WriteByte(0)
+		}
+		var v byte
+		if d.LSBFirst {
+			v = byte(x>>uint(i)&1) << d.bit
+		} else {
+			v = byte(x>>uint(n-1-i)&1) << (7 - d.bit)
+		}
+		d.buf.Bytes()[d.ii] |= v
+		d.bit++
+		if d.bit == 8 {
+			d.ii++
+			d.bit = 0
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package bytefmt
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestMarshal(t *testing.T) {
+	buf := []byte("RIFF\x02\x01\x80\x00\xff\xff\xff\xfe\x3aab\x00\x3f\xc0\x00\x00\xac\x02hi!")
+	var h testHeader
+	if err := Unmarshal(buf, &h); err != nil {
+		t.Logf("unexpected error %v", err)
+		t.Fail()
+	}
+	res, err := Marshal(&h)
+	if err != nil || !bytes.Equal(res, buf) {
+		t.Logf("round trip: unexpected %q, %v", res, err)
+		t.Fail()
+	}
+	var tests = []struct {
+		v      interface{}
+		expect []byte
+	}{
+		{struct {
+			A uint16 `bytefmt:"2d"`
+			B uint16 `bytefmt:"-2x"`
+			C int8   `bytefmt:"+1d"`
+		}{0x0102, 0x0304, -1}, []byte{1, 2, 4, 3, 0xff}},
+		{struct {
+			A int  `bytefmt:".3d"`
+			B int  `bytefmt:"+.5d"`
+			C byte `bytefmt:"v"`
+		}{5, -1, 9}, []byte{0xbf, 9}},
+		{struct {
+			A int `bytefmt:"4.2d"`
+			B int `bytefmt:"-4.2x"`
+		}{0x0102, 0x0304}, []byte{0, 0, 1, 2, 4, 3, 0, 0}},
+		{struct {
+			S string `bytefmt:"4s"`
+			T string `bytefmt:"2a"`
+			Z []byte `bytefmt:"Z"`
+		}{"ab", "xyz", []byte("c")}, []byte("ab\x00\x00xyc\x00")},
+		{struct {
+			A byte `bytefmt:"1d"`
+			_ int  `bytefmt:"4@"`
+			B byte `bytefmt:"1d"`
+			_ int  `bytefmt:"2z"`
+		}{A: 1, B: 2}, []byte{1, 0, 0, 0, 2, 0, 0}},
+		{struct {
+			W uint64  `bytefmt:"w"`
+			Z int     `bytefmt:"W"`
+			F float64 `bytefmt:"8f"`
+		}{300, -2, 1.5}, []byte{0xac, 0x02, 0x03, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		res, err := Marshal(tt.v)
+		if err != nil || !bytes.Equal(res, tt.expect) {
+			t.Logf("%+v: expected %q, res %q, %v", tt.v, tt.expect, res, err)
+			t.Fail()
+		}
+	}
+	var errs = []struct {
+		v       interface{}
+		problem string
+	}{
+		{struct {
+			N uint16 `bytefmt:"1d"`
+		}{256}, "bad value 256"},
+		{struct {
+			N int `bytefmt:"2d"`
+		}{-1}, "bad value -1"},
+		{struct {
+			N int `bytefmt:"+1d"`
+		}{128}, "bad value 128"},
+		{struct {
+			N int `bytefmt:".3d"`
+		}{8}, "bad value 8"},
+		{struct {
+			N string `bytefmt:"2d"`
+		}{"x"}, "cannot encode N of type string"},
+		{struct {
+			F float32 `bytefmt:"3f"`
+		}{1}, "bad width 3"},
+		{struct {
+			N int `bytefmt:"1.4d"`
+		}{1}, "bad width 4"},
+		{struct {
+			N int `bytefmt:"4.2d"`
+		}{0x10000}, "bad value 65536"},
+		{struct {
+			A int `bytefmt:"4d"`
+			_ int `bytefmt:"2@"`
+		}{}, "_: offset 2 already passed"},
+		{struct {
+			N int `bytefmt:"(bits)"`
+		}{}, "N: named verbs are not supported"},
+		{struct {
+			S string `bytefmt:"{n}s"`
+		}{}, "S: width arguments are not supported"},
+	}
+	for _, tt := range errs {
+		_, err := Marshal(tt.v)
+		if e, ok := err.(*FormatError); !ok || e.Problem != tt.problem {
+			t.Logf("%+v: unexpected error %v", tt.v, err)
+			t.Fail()
+		}
+	}
+	if _, err := Marshal(1); err == nil {
+		t.Logf("non struct: no error")
+		t.Fail()
+	}
+}
+
+func TestMarshalFloats(t *testing.T) {
+	type floats struct {
+		H  float32 `bytefmt:"2f"`
+		HL float64 `bytefmt:"-2f"`
+		E  float64 `bytefmt:"10f"`
+		EL float64 `bytefmt:"-10f"`
+	}
+	v := floats{1.5, math.Ldexp(3, -24), 1.5, -1e300}
+	b, err := Marshal(&v)
+	if err != nil || !bytes.Equal(b[:14], []byte{0x3e, 0x00, 0x03, 0x00, 0x3f, 0xff, 0xc0, 0, 0, 0, 0, 0, 0, 0}) {
+		t.Logf("marshal: unexpected %x, %v", b, err)
+		t.Fail()
+	}
+	var res floats
+	if err := Unmarshal(b, &res); err != nil || res != v {
+		t.Logf("round trip: unexpected %+v, %v", res, err)
+		t.Fail()
+	}
+	big := struct {
+		H float64 `bytefmt:"2f"`
+	}{70000}
+	if _, err := Marshal(&big); err == nil {
+		t.Logf("half precision overflow: no error")
+		t.Fail()
+	}
+}
+
+func TestMarshalSlots(t *testing.T) {
+	type slots struct {
+		D  int    `bytefmt:"4d"`
+		DP int    `bytefmt:"4.2d"`
+		DN int    `bytefmt:"+-4.2d"`
+		DB int    `bytefmt:".5d"`
+		X  uint32 `bytefmt:"4x"`
+		XP uint32 `bytefmt:"-4.2x"`
+		XN uint32 `bytefmt:".2x"`
+		O  uint32 `bytefmt:"4o"`
+		OP uint32 `bytefmt:"4.2o"`
+		B  uint32 `bytefmt:"4b"`
+		BP uint32 `bytefmt:"4.2b"`
+	}
+	v := slots{0x12345, 0x1234, -2, 17, 0x12345, 0x1234, 0x12345, 0x12345, 0x12345, 0x12345, 0x12345}
+	b, err := Marshal(&v)
+	if err != nil || len(b) != 10*4+1 {
+		t.Logf("marshal: unexpected %x, %v", b, err)
+		t.Fail()
+	}
+	var res slots
+	if err := Unmarshal(b, &res); err != nil || res != v {
+		t.Logf("round trip: unexpected %+v, %v", res, err)
+		t.Fail()
+	}
+	// The prec of %o is no slot, as in formatting.
+	if s := Sprintf(b[29:], "%4.2o"); s != "221505" {
+		t.Logf("Sprintf: unexpected %q", s)
+		t.Fail()
+	}
+}
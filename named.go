@@ -0,0 +1,168 @@
+package bytefmt
+
+import (
+	"strconv"
+	"strings"
+)
+
+// namedVerbs maps the names understood inside %(...) to their
+// implementation. It is filled in by init to avoid an initialization
+// loop, as some named verbs recurse into doDump.
+var namedVerbs map[string]func(d *Dumper, a []interface{})
+
+func init() {
+	namedVerbs = map[string]func(d *Dumper, a []interface{}){
+		"ip6prefix":  (*Dumper).fmtIP6Prefix,
+		"bcdtime":    (*Dumper).fmtBCDTime,
+		"compsize":   (*Dumper).fmtCompSize,
+		"reserved":   (*Dumper).fmtReserved,
+		"fixrow":     (*Dumper).fmtFixRow,
+		"parity":     (*Dumper).fmtParity,
+		"bits":       (*Dumper).fmtBits,
+		"rice":       (*Dumper).fmtRice,
+		"present":    (*Dumper).fmtPresent,
+		"mp4matrix":  (*Dumper).fmtMP4Matrix,
+		"kvmap":      (*Dumper).fmtKVMap,
+		"fixsplit":   (*Dumper).fmtFixSplit,
+		"enums":      (*Dumper).fmtEnums,
+		"exptime":    (*Dumper).fmtExpTime,
+		"rgba16f":    (*Dumper).fmtRGBA16F,
+		"deltas":     (*Dumper).fmtDeltas,
+		"gain":       (*Dumper).fmtGain,
+		"signhex":    (*Dumper).fmtSignHex,
+		"crcblob":    (*Dumper).fmtCRCBlob,
+		"bounded":    (*Dumper).fmtBounded,
+		"bitfloat":   (*Dumper).fmtBitFloat,
+		"tagged":     (*Dumper).fmtTagged,
+		"norm":       (*Dumper).fmtNorm,
+		"dosdate":    (*Dumper).fmtDOSDate,
+		"agg":        (*Dumper).fmtAgg,
+		"qauto":      (*Dumper).fmtQAuto,
+		"minifloat":  (*Dumper).fmtMiniFloat,
+		"grid":       (*Dumper).fmtGrid,
+		"asciinums":  (*Dumper).fmtASCIINums,
+		"bcdpct":     (*Dumper).fmtBCDPercent,
+		"utf16bom":   (*Dumper).fmtUTF16BOM,
+		"enumgroups": (*Dumper).fmtEnumGroups,
+		"csv":        (*Dumper).fmtCSV,
+		"taitime":    (*Dumper).fmtTAITime,
+		"palette":    (*Dumper).fmtPalette,
+		"b64blob":    (*Dumper).fmtBase64Blob,
+		"delta":      (*Dumper).fmtDelta,
+		"sentinel":   (*Dumper).fmtSentinel,
+		"interp":     (*Dumper).fmtInterp,
+		"adc":        (*Dumper).fmtADC,
+		"mime":       (*Dumper).fmtMIME,
+		"hexfix":     (*Dumper).fmtHexFix,
+		"fields":     (*Dumper).fmtFields,
+		"asciitime":  (*Dumper).fmtASCIITime,
+		"popcount":   (*Dumper).fmtPopCount,
+		"signmag":    (*Dumper).fmtSignMag,
+		"excess":     (*Dumper).fmtExcess,
+		"lendelim":   (*Dumper).fmtLenDelim,
+		"asciiint":   (*Dumper).fmtASCIIInt,
+		"bytesum":    (*Dumper).fmtByteSum,
+		"fourcc":     (*Dumper).fmtFourCC,
+		"percent":    (*Dumper).fmtPercent,
+		"oid":        (*Dumper).fmtOID,
+		"filetime":   (*Dumper).fmtFiletime,
+		"bar":        (*Dumper).fmtBar,
+		"duration":   (*Dumper).fmtDuration,
+		"vlq":        (*Dumper).fmtVLQ,
+		"entropy":    (*Dumper).fmtEntropy,
+		"ntp":        (*Dumper).fmtNTPTime,
+		"size":       (*Dumper).fmtSize,
+	}
+}
+
+// doNamed dispatches the named verb given by spec, which is the verb
+// name optionally followed by a colon and its parameters.
+func (d *Dumper) doNamed(spec string, a []interface{}) {
+	name := spec
+	d.params = nil
+	if i := strings.IndexByte(spec, ':'); i >= 0 {
+		name = spec[:i]
+		d.params = strings.Split(spec[i+1:], ",")
+	}
+	f, ok := namedVerbs[name]
+	if !ok {
+		d.unknownVerb("(" + spec + ")")
+		return
+	}
+	f(d, a)
+}
+
+// paramInt returns the i'th parameter of a named verb, or def if it is
+// missing or not a number.
+func (d *Dumper) paramInt(i int, def int) int {
+	if i >= len(d.params) {
+		return def
+	}
+	n, err := strconv.Atoi(d.params[i])
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// A VerbFunc formats a verb letter registered with RegisterVerb. It is
+// passed the rest of the input, or only width bytes if the format has a
+// width, the width of the format or -1 without
+// one, and whether the - flag asked for little endian byte order. It
+// returns the text to print and the number of bytes of b it consumed.
+// An error is printed as BadValue followed by its text instead of the
+// text, or stops a Strict Dumper.
+type VerbFunc func(b []byte, width int, littleEndian bool) (string, int, error)
+
+// RegisterVerb makes d format the verb letter c with fn. It panics if fn
+// is nil, if c is a built-in verb, a flag, a digit, one of *{.( which
+// start other parts of a format or not a printable ASCII character.
+func (d *Dumper) RegisterVerb(c byte, fn VerbFunc) {
+	if strings.IndexByte(letterVerbs, c) >= 0 {
+		panic("bytefmt: RegisterVerb of built-in verb " + string(c))
+	}
+	d.OverrideVerb(c, fn)
+}
+
+// OverrideVerb is like RegisterVerb, but may replace a built-in verb
+// other than %%.
+func (d *Dumper) OverrideVerb(c byte, fn VerbFunc) {
+	var s spec
+	if fn == nil {
+		panic("bytefmt: RegisterVerb with nil func")
+	}
+	if s.setFlag(c) || c >= '0' && c <= '9' || c <= ' ' || c >= 0x7f || strings.IndexByte("%*{.(", c) >= 0 {
+		panic("bytefmt: cannot register verb " + strconv.QuoteRune(rune(c)))
+	}
+	if d.verbs == nil {
+		d.verbs = make(map[byte]VerbFunc)
+	}
+	d.verbs[c] = fn
+}
+
+// doRegistered formats a verb registered with RegisterVerb.
+func (d *Dumper) doRegistered(fn VerbFunc) {
+	d.alignByte()
+	width := -1
+	var b []byte
+	if d.widthValid {
+		width = d.width
+		d.fill(d.ii + width)
+		b = d.input[d.ii:]
+		if len(b) > width {
+			b = b[:width]
+		}
+	} else {
+		b = d.input[d.ii : d.ii+d.remaining()]
+	}
+	s, n, err := fn(b, width, d.intel)
+	if n < 0 || n > len(b) {
+		panic(&TruncatedError{Verb: d.verb, Offset: d.ii})
+	}
+	d.ii += n
+	if err != nil {
+		d.bad(BadValue+err.Error(), err.Error())
+		return
+	}
+	d.buf.WriteString(s)
+}
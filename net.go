@@ -0,0 +1,47 @@
+package bytefmt
+
+import (
+	"net"
+	"strconv"
+)
+
+// fmtIP prints a 4 (default) byte IPv4 or 16 byte IPv6 address. Other
+// widths are flagged with BadWidth and skipped.
+func (d *Dumper) fmtIP() {
+	if !d.widthValid {
+		d.width = net.IPv4len
+	}
+	if d.width != net.IPv4len && d.width != net.IPv6len {
+		d.badWidth(d.width)
+		d.fetchBytes(d.width)
+		return
+	}
+	d.buf.WriteString(net.IP(d.fetchBytes(d.width)).String())
+}
+
+// fmtMAC prints a 6 (default) byte MAC or 8 byte EUI-64 address. Other
+// widths are flagged with BadWidth and skipped.
+func (d *Dumper) fmtMAC() {
+	if !d.widthValid {
+		d.width = 6
+	}
+	if d.width != 6 && d.width != 8 {
+		d.badWidth(d.width)
+		d.fetchBytes(d.width)
+		return
+	}
+	d.buf.WriteString(net.HardwareAddr(d.fetchBytes(d.width)).String())
+}
+
+// fmtIP6Prefix prints a 16 byte IPv6 address followed by a one byte
+// prefix length in CIDR notation.
+func (d *Dumper) fmtIP6Prefix(a []interface{}) {
+	ip := net.IP(d.fetchBytes(net.IPv6len))
+	n := int(d.fetchBytes(1)[0])
+	d.buf.WriteString(ip.String())
+	d.buf.WriteRune('/')
+	if n > 8*net.IPv6len {
+		d.badValue("")
+	}
+	d.buf.WriteString(strconv.Itoa(n))
+}
@@ -0,0 +1,71 @@
+package bytefmt
+
+import (
+	"testing"
+)
+
+func TestIP6Prefix(t *testing.T) {
+	buf := []byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 32}
+	res := Sprintf(buf, "%(ip6prefix)")
+	expected := "2001:db8::/32"
+	if res != expected {
+		t.Logf("ip6prefix expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	buf[16] = 200
+	res = Sprintf(buf, "%(ip6prefix)")
+	expected = "2001:db8::/%%BADVALUE%200"
+	if res != expected {
+		t.Logf("ip6prefix expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf(buf, "%(ip6)")
+	expected = "%%UNKOWN%(ip6)"
+	if res != expected {
+		t.Logf("ip6prefix expected %q, res %q", expected, res)
+		t.Fail()
+	}
+}
+
+func TestIP(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{192, 168, 1, 2}, "%I", "192.168.1.2"},
+		{[]byte{192, 168, 1, 2}, "%-4I", "192.168.1.2"},
+		{[]byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}, "%16I", "2001:db8::1"},
+		{[]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 10, 0, 0, 1}, "%16I", "10.0.0.1"},
+		{[]byte{1, 2, 3, 4, 5, 6}, "%6I", "%%BADWIDTH%6"},
+		{[]byte{1, 2, 3, 4, 5, 6, 7}, "%6I%1x", "%%BADWIDTH%67"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestMAC(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab}, "%M", "01:23:45:67:89:ab"},
+		{[]byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab}, "%-6M", "01:23:45:67:89:ab"},
+		{[]byte{0x02, 0, 0x5e, 0x10, 0, 0, 0, 1}, "%8M", "02:00:5e:10:00:00:00:01"},
+		{[]byte{1, 2, 3, 4}, "%4M", "%%BADWIDTH%4"},
+		{[]byte{1, 2, 3, 4}, "%3M%1d", "%%BADWIDTH%34"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
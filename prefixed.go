@@ -0,0 +1,146 @@
+package bytefmt
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// fmtCompSize prints the compressed and uncompressed size fields of an
+// archive entry with the compression ratio, skipping the compressed
+// data that follows them.
+func (d *Dumper) fmtCompSize(a []interface{}) {
+	if !d.widthValid {
+		d.width = 4
+	}
+	comp := d.fetchInt()
+	uncomp := d.fetchInt()
+	d.fetchBytes(int(comp))
+	d.buf.WriteString(strconv.FormatInt(comp, 10))
+	d.buf.WriteRune('/')
+	d.buf.WriteString(strconv.FormatInt(uncomp, 10))
+	if uncomp != 0 {
+		d.buf.WriteString(" (")
+		d.buf.WriteString(strconv.FormatFloat(100*float64(comp)/float64(uncomp), 'f', d.prec, 64))
+		d.buf.WriteString("%)")
+	}
+}
+
+// fetchPrefixed consumes a length field of d.width bytes and the number
+// of bytes it gives.
+func (d *Dumper) fetchPrefixed() []byte {
+	return d.fetchBytes(int(d.fetchInt()))
+}
+
+// fmtKVMap prints a count followed by that many length prefixed key and
+// value pairs as key=value lines. The count and the lengths are width
+// (default 1) bytes.
+func (d *Dumper) fmtKVMap(a []interface{}) {
+	if !d.widthValid {
+		d.width = 1
+	}
+	for n := d.fetchInt(); n > 0; n-- {
+		k := d.fetchPrefixed()
+		d.writeLines(string(k) + "=" + string(d.fetchPrefixed()) + "\n")
+	}
+}
+
+// fmtCRCBlob prints the length and checksum of a length prefixed blob
+// with a width (default 2) byte length, skipping the blob. The checksum
+// algorithm is the string argument selected by prec, crc32 by default.
+func (d *Dumper) fmtCRCBlob(a []interface{}) {
+	if !d.widthValid {
+		d.width = 2
+	}
+	alg := "crc32"
+	if d.precValid {
+		alg = d.argString(a, d.prec)
+	}
+	b := d.fetchPrefixed()
+	d.buf.WriteString("len=" + strconv.Itoa(len(b)) + " crc=")
+	sum, digits, ok := checksum(alg, b)
+	if !ok {
+		d.badValue(alg)
+		return
+	}
+	d.writeHex(sum, digits)
+}
+
+// dumpRegion formats b on its own with fmt, as if it were the whole
+// input.
+func (d *Dumper) dumpRegion(b []byte, fmt string, a []interface{}) {
+	input, ii, bit, mark, src := d.input, d.ii, d.bit, d.mark, d.src
+	defer func() { d.input, d.ii, d.bit, d.mark, d.src = input, ii, bit, mark, src }()
+	d.input, d.ii, d.bit, d.mark, d.src = b, 0, 0, 0, nil
+	d.doDump(fmt, a)
+}
+
+// fmtTagged prints a tag byte and width (default 1) byte length
+// prefixed value using the format for the tag in the map[int64]string
+// argument selected by prec. Values with unknown tags are hex dumped.
+func (d *Dumper) fmtTagged(a []interface{}) {
+	if !d.widthValid {
+		d.width = 1
+	}
+	tag := int64(d.fetchBytes(1)[0])
+	b := d.fetchPrefixed()
+	if f, ok := d.argMap(a, d.prec)[tag]; ok {
+		d.dumpRegion(b, f, a)
+	} else {
+		d.hexDump(b)
+	}
+}
+
+// fmtLenDelim prints a protobuf length delimited field, a varint length
+// followed by that many bytes, formatted with the string argument
+// selected by prec. Without prec the bytes are printed in hex as by %h.
+func (d *Dumper) fmtLenDelim(a []interface{}) {
+	n, ok := d.fetchVarint()
+	if !ok || n > math.MaxInt32 {
+		d.badValue("")
+		return
+	}
+	f := "%h"
+	if d.precValid {
+		f = d.argString(a, d.prec)
+	}
+	d.dumpRegion(d.fetchBytes(int(n)), f, a)
+}
+
+// fmtBase64Blob prints a length prefixed blob with a width (default 2)
+// byte length in standard base64, or URL safe base64 with the # flag.
+func (d *Dumper) fmtBase64Blob(a []interface{}) {
+	if !d.widthValid {
+		d.width = 2
+	}
+	d.writeBase64(d.fetchPrefixed())
+}
+
+// writeBase64 prints b in standard base64, or URL safe base64 with the
+// # flag.
+func (d *Dumper) writeBase64(b []byte) {
+	enc := base64.StdEncoding
+	if d.altFlag {
+		enc = base64.URLEncoding
+	}
+	d.buf.WriteString(enc.EncodeToString(b))
+}
+
+// fmtMIME prints the sniffed content type of a length prefixed blob with
+// a width (default 2) byte length, skipping the blob. The # flag adds a
+// hex preview of the first bytes.
+func (d *Dumper) fmtMIME(a []interface{}) {
+	if !d.widthValid {
+		d.width = 2
+	}
+	b := d.fetchPrefixed()
+	d.buf.WriteString(http.DetectContentType(b))
+	if d.altFlag && len(b) > 0 {
+		if len(b) > 8 {
+			b = b[:8]
+		}
+		d.buf.WriteString(" " + hex.EncodeToString(b))
+	}
+}
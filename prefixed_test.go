@@ -0,0 +1,150 @@
+package bytefmt
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCompSize(t *testing.T) {
+	buf := append([]byte{0, 4, 0, 16}, bytes.Repeat([]byte{0xaa}, 4)...)
+	buf = append(buf, 0x42)
+	res := Sprintf(buf, "%2(compsize) %1x")
+	expected := "4/16 (25%) 42"
+	if res != expected {
+		t.Logf("compsize expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf([]byte{0, 0, 0, 1, 0, 0, 0, 3, 0}, "%.2(compsize)")
+	expected = "1/3 (33.33%)"
+	if res != expected {
+		t.Logf("compsize expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf([]byte{0, 0, 0, 0}, "%1(compsize)")
+	expected = "0/0"
+	if res != expected {
+		t.Logf("compsize expected %q, res %q", expected, res)
+		t.Fail()
+	}
+}
+
+func TestKVMap(t *testing.T) {
+	buf := []byte("\x02\x04name\x03foo\x04mode\x02rw")
+	res := Sprintf(buf, "%(kvmap)")
+	expected := "name=foo\nmode=rw\n"
+	if res != expected {
+		t.Logf("kvmap expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	buf = []byte("\x00\x01\x00\x01k\x00\x00")
+	res = Sprintf(buf, "%2(kvmap)")
+	expected = "k=\n"
+	if res != expected {
+		t.Logf("kvmap expected %q, res %q", expected, res)
+		t.Fail()
+	}
+}
+
+func TestCRCBlob(t *testing.T) {
+	buf := append([]byte{0x00, 0x09}, "123456789"...)
+	buf = append(buf, 0x42)
+	res := Sprintf(buf, "%(crcblob) %1x")
+	expected := "len=9 crc=0xcbf43926 42"
+	if res != expected {
+		t.Logf("crcblob expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf(buf, "%.0(crcblob)", "crc16")
+	expected = "len=9 crc=0xbb3d"
+	if res != expected {
+		t.Logf("crcblob expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf([]byte{0x00, 0x00}, "%.0(crcblob)", "md5")
+	expected = "len=0 crc=%%BADVALUE%md5"
+	if res != expected {
+		t.Logf("crcblob expected %q, res %q", expected, res)
+		t.Fail()
+	}
+}
+
+func TestTagged(t *testing.T) {
+	var variants = map[int64]string{
+		1: "point(%2d, %2d)",
+		2: "name %s",
+	}
+	buf := []byte("\x01\x04\x00\x01\x00\x02\x02\x03abc\x07\x02\xde\xad")
+	res := Sprintf(buf, "%.0(tagged); %.0(tagged); %.0(tagged)", variants)
+	expected := "point(1, 2); name abc; 00000000  de ad                                             |..|\n"
+	if res != expected {
+		t.Logf("tagged expected %q, res %q", expected, res)
+		t.Fail()
+	}
+}
+
+func TestBase64Blob(t *testing.T) {
+	buf := []byte{0x00, 0x05, 'h', 'e', 'l', 'l', 'o', 0x42}
+	res := Sprintf(buf, "%(b64blob) %1x")
+	expected := "aGVsbG8= 42"
+	if res != expected {
+		t.Logf("b64blob expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf([]byte{0x02, 0xfb, 0xff}, "%1(b64blob)")
+	expected = "+/8="
+	if res != expected {
+		t.Logf("b64blob expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf([]byte{0x02, 0xfb, 0xff}, "%#1(b64blob)")
+	expected = "-_8="
+	if res != expected {
+		t.Logf("b64blob expected %q, res %q", expected, res)
+		t.Fail()
+	}
+}
+
+func TestMIME(t *testing.T) {
+	png := []byte("\x00\x0c\x89PNG\r\n\x1a\n\x00\x00\x00\x0d")
+	res := Sprintf(png, "%(mime)")
+	expected := "image/png"
+	if res != expected {
+		t.Logf("mime expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf(png, "%#(mime)")
+	expected = "image/png 89504e470d0a1a0a"
+	if res != expected {
+		t.Logf("mime expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf([]byte("\x05hello\x42"), "%1(mime) %1x")
+	expected = "text/plain; charset=utf-8 42"
+	if res != expected {
+		t.Logf("mime expected %q, res %q", expected, res)
+		t.Fail()
+	}
+}
+
+func TestLenDelim(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte("\x03abc"), "%.0(lendelim)", "\"abc\""},
+		{[]byte("\x02\xde\xad\x01"), "%(lendelim) %1d", "de:ad 1"},
+		{[]byte("\x00\x07"), "%(lendelim)|%1d", "|7"},
+		{[]byte("\x04\x00\x01\x00\x02\x09"), "%.1(lendelim) %1d", "point(1, 2) 9"},
+		{append([]byte{0x80, 0x01}, make([]byte, 128)...), "%.2(lendelim)%1d", ".%%EOF%"},
+		{[]byte("\x05ab"), "%(lendelim)", "%%EOF%"},
+		{[]byte("\xff\xff\xff\xff\xff\xff\xff\xff\xff\x7f"), "%(lendelim)", "%%BADVALUE%"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt, "%q", "point(%2d, %2d)", "%128z.")
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package bytefmt
+
+import (
+	"io"
+	"math"
+)
+
+// NewReaderDumper returns a Dumper that reads its input from r on
+// demand, so that formatting a header of a large region only reads the
+// bytes the formats consume. The buf passed to its methods holds the
+// first bytes of the input, usually none, and r is read from offset
+// len(buf) onwards. Formats that default to the rest of the input read
+// r up to the end, an io.SectionReader gives r a defined length.
+func NewReaderDumper(r io.ReaderAt) *Dumper {
+	return &Dumper{at: r}
+}
+
+// setInput starts the input with buf, followed by the bytes of d.at if
+// d was made by NewReaderDumper.
+func (d *Dumper) setInput(buf []byte) {
+	d.input = buf
+	if d.at != nil {
+		// Bytes read later go to a copy, never to the spare
+		// capacity of buf.
+		d.input = buf[:len(buf):len(buf)]
+		d.src = io.NewSectionReader(d.at, int64(len(buf)), math.MaxInt64-int64(len(buf)))
+	}
+}
@@ -0,0 +1,58 @@
+package bytefmt
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// countingReaderAt records the furthest offset read from it.
+type countingReaderAt struct {
+	r   io.ReaderAt
+	max int64
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	if end := off + int64(n); end > c.max {
+		c.max = end
+	}
+	return n, err
+}
+
+func TestReaderDumper(t *testing.T) {
+	data := append([]byte{0, 2, 'h', 'i'}, make([]byte, 1<<20)...)
+	r := &countingReaderAt{r: bytes.NewReader(data)}
+	d := NewReaderDumper(r)
+	res, n := d.SprintfN(nil, "%2d %2s")
+	if res != "2 hi" || n != 4 || r.max != 4 {
+		t.Logf("header: unexpected %q, consumed %d, read %d", res, n, r.max)
+		t.Fail()
+	}
+	buf := make([]byte, 1, 16)
+	buf[0] = 7
+	res = d.Sprintf(buf, "%1d %1d %1d")
+	if res != "7 2 104" || buf[:2][1] != 0 {
+		t.Logf("with buf: unexpected %q", res)
+		t.Fail()
+	}
+	small := NewReaderDumper(io.NewSectionReader(bytes.NewReader(data), 2, 2))
+	if res := small.Sprintf(nil, "%q"); res != `"hi"` {
+		t.Logf("section: unexpected %q", res)
+		t.Fail()
+	}
+	runes := NewReaderDumper(bytes.NewReader([]byte("日本")))
+	if res := runes.Sprintf(nil, "%c|%#1q"); res != `日|"本"` {
+		t.Logf("runes: unexpected %q", res)
+		t.Fail()
+	}
+	if res := small.Sprintf(nil, "%4s"); res != "%%EOF%" {
+		t.Logf("section past end: unexpected %q", res)
+		t.Fail()
+	}
+	d.Reset()
+	if res := d.Sprintf(nil, "%1d"); res != "0" {
+		t.Logf("reset: unexpected %q", res)
+		t.Fail()
+	}
+}
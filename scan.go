@@ -0,0 +1,46 @@
+package bytefmt
+
+import (
+	"io"
+)
+
+// ScanRecord formats the next record of r with fmt, reading only as
+// many bytes as the formats consume. Formats that default to the rest of
+// the input read r to its end, so that the record takes the rest of the
+// stream. ScanRecord returns io.EOF if r is exhausted before the record
+// starts, and io.ErrUnexpectedEOF, along with the output up to the
+// Truncated marker, if it ends within the record.
+func ScanRecord(r io.Reader, fmt string, a ...interface{}) (string, error) {
+	d := getDumper()
+	s, err := d.ScanRecord(r, fmt, a...)
+	putDumper(d)
+	return s, err
+}
+
+// ScanRecord is like the package level ScanRecord, reusing d.
+func (d *Dumper) ScanRecord(r io.Reader, fmt string, a ...interface{}) (string, error) {
+	d.Reset()
+	d.src = r
+	if eof, err := d.atEOF(); eof || err != nil {
+		if err == nil {
+			err = io.EOF
+		}
+		return "", err
+	}
+	err := d.dump(fmt, a)
+	if _, ok := err.(*TruncatedError); ok {
+		return d.buf.String(), io.ErrUnexpectedEOF
+	}
+	return d.buf.String(), err
+}
+
+// atEOF reports whether the input is exhausted, reading a byte from the
+// source reader if need be.
+func (d *Dumper) atEOF() (eof bool, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = d.stopped(e)
+		}
+	}()
+	return !d.available(1), nil
+}
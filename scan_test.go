@@ -0,0 +1,81 @@
+package bytefmt
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestScanRecord(t *testing.T) {
+	r := iotest.OneByteReader(bytes.NewReader([]byte{0, 1, 'a', 0, 2, 'b', 0}))
+	d := NewDumper()
+	var res []string
+	for {
+		s, err := d.ScanRecord(r, "%2d=%1s")
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			res = append(res, s+"|"+err.Error())
+			break
+		}
+		res = append(res, s)
+	}
+	if len(res) != 3 || res[0] != "1=a" || res[1] != "2=b" || res[2] != "%%EOF%|"+io.ErrUnexpectedEOF.Error() {
+		t.Logf("unexpected records %q", res)
+		t.Fail()
+	}
+	s, err := ScanRecord(bytes.NewReader([]byte("ab\x05rest")), "%2z%1d %s")
+	if s != "5 rest" || err != nil {
+		t.Logf("unexpected %q %v", s, err)
+		t.Fail()
+	}
+	s, err = ScanRecord(bytes.NewReader([]byte{0xf0, 0x12}), "%4(bits) %3@%1d")
+	if s != "15 %%EOF%" || err != io.ErrUnexpectedEOF {
+		t.Logf("unexpected %q %v", s, err)
+		t.Fail()
+	}
+	r = bytes.NewReader([]byte("ab日"))
+	res = res[:0]
+	for {
+		s, err := d.ScanRecord(r, "%c")
+		if err != nil {
+			break
+		}
+		res = append(res, s)
+	}
+	if len(res) != 3 || res[0] != "a" || res[1] != "b" || res[2] != "日" {
+		t.Logf("runes: unexpected records %q", res)
+		t.Fail()
+	}
+	r = bytes.NewReader([]byte{0, 1, 0xff, 0xff, 0, 2, 0xff, 0xff})
+	res = res[:0]
+	for i := 0; i < 4; i++ {
+		s, err := d.ScanRecord(r, "%.0(sentinel)", int64(0xffff))
+		if err == io.EOF {
+			break
+		}
+		res = append(res, s)
+	}
+	if len(res) != 2 || res[0] != "1" || res[1] != "2" {
+		t.Logf("sentinel: unexpected records %q", res)
+		t.Fail()
+	}
+	r = bytes.NewReader([]byte("rest"))
+	if s, err := d.ScanRecord(r, "%s"); s != "rest" || err != nil {
+		t.Logf("rest: unexpected %q %v", s, err)
+		t.Fail()
+	}
+	if _, err := d.ScanRecord(r, "%s"); err != io.EOF {
+		t.Logf("rest: expected io.EOF, got %v", err)
+		t.Fail()
+	}
+	boom := errors.New("boom")
+	_, err = ScanRecord(iotest.ErrReader(boom), "%1d")
+	if err != boom {
+		t.Logf("expected read error, got %v", err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,78 @@
+package bytefmt
+
+import (
+	"io"
+	"strconv"
+)
+
+// SprintfStrict is like Sprintf with a Strict Dumper, returning the
+// output up to the first problem and an error describing it: a
+// *FormatError for a bad value or width, an unknown verb or a malformed
+// format, or, as Sprintf would stop there too, a *TruncatedError for a
+// read past the end of buf, a *WidthError for an int wider than 8 bytes
+// and an *ArgError for a missing argument. The output of the latter
+// three ends with their marker.
+func SprintfStrict(buf []byte, fmt string, a ...interface{}) (string, error) {
+	d := getDumper()
+	d.Strict = true
+	d.setInput(buf)
+	err := d.dump(fmt, a)
+	s := d.buf.String()
+	d.Strict = false
+	putDumper(d)
+	return s, err
+}
+
+// FprintfStrict is like Fprintf with a Strict Dumper.
+func FprintfStrict(w io.Writer, buf []byte, fmt string, a ...interface{}) (n int, err error) {
+	d := getDumper()
+	d.Strict = true
+	n, err = d.Fprintf(w, buf, fmt, a...)
+	d.Strict = false
+	putDumper(d)
+	return
+}
+
+// fail stops formatting with a *FormatError for problem in the current
+// format.
+func (d *Dumper) fail(problem string) {
+	panic(&FormatError{Pos: d.pos, Verb: d.verb, Offset: d.ii, Problem: problem})
+}
+
+// bad prints marker for problem in the current format, unless d is
+// Strict. A Strict Dumper, or one with StopOnError set, then stops
+// formatting with a *FormatError.
+func (d *Dumper) bad(marker, problem string) {
+	if !d.Strict {
+		d.buf.WriteString(marker)
+	}
+	if d.Strict || d.StopOnError {
+		d.fail(problem)
+	}
+}
+
+// badValue reports s, a decoded value that is out of range for the
+// format, with BadValue.
+func (d *Dumper) badValue(s string) {
+	problem := "bad value"
+	if s != "" {
+		problem += " " + s
+	}
+	d.bad(BadValue+s, problem)
+}
+
+// badWidth reports a width w that the format does not support with
+// BadWidth.
+func (d *Dumper) badWidth(w int) {
+	d.bad(BadWidth+strconv.Itoa(w), "bad width "+strconv.Itoa(w))
+}
+
+// unknownVerb reports the unknown verb s with UnknownFormat, or prints
+// the format as is if the Dumper has PassthroughUnknown set.
+func (d *Dumper) unknownVerb(s string) {
+	if d.PassthroughUnknown {
+		d.buf.WriteString(d.verb)
+		return
+	}
+	d.bad(UnknownFormat+s, "unknown verb "+s)
+}
@@ -0,0 +1,101 @@
+package bytefmt
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStrict(t *testing.T) {
+	var tests = []struct {
+		buf     []byte
+		fmt     string
+		expect  string
+		pos     int
+		verb    string
+		offset  int
+		problem string
+	}{
+		{[]byte{1, 2}, "%1d %1d", "1 2", 0, "", 0, ""},
+		{[]byte{1, 2}, "%1d %!", "1 ", 4, "%!", 1, "unknown verb !"},
+		{[]byte{1, 2}, "%1d %(nosuch)", "1 ", 4, "%(nosuch)", 1, "unknown verb (nosuch)"},
+		{[]byte{1, 2, 3, 4, 5, 6}, "%6I", "", 0, "%6I", 0, "bad width 6"},
+		{append(make([]byte, 16), 200), "%(ip6prefix)", "::/", 0, "%(ip6prefix)", 17, "bad value"},
+		{[]byte{0, 2}, "%1d %4.", "0 ", 4, "%4.", 1, "incomplete format"},
+		{[]byte{1, 0x3f}, "%1.0e %1.0e", "one ", 6, "%1.0e", 2, "bad value 63"},
+	}
+	for _, tt := range tests {
+		d := NewDumper()
+		d.Strict = true
+		d.StrictEnum = true
+		var b bytes.Buffer
+		_, err := d.Fprintf(&b, tt.buf, tt.fmt, map[int64]string{1: "one"})
+		res := b.String()
+		if tt.problem == "" {
+			if err != nil || res != tt.expect {
+				t.Logf("format %q: unexpected %q, %v", tt.fmt, res, err)
+				t.Fail()
+			}
+			continue
+		}
+		e, ok := err.(*FormatError)
+		if !ok || res != tt.expect || e.Pos != tt.pos || e.Verb != tt.verb || e.Offset != tt.offset || e.Problem != tt.problem {
+			t.Logf("format %q: expected %q and %q at %d (%s, offset %d), got %q and %+v", tt.fmt, tt.expect, tt.problem, tt.pos, tt.verb, tt.offset, res, err)
+			t.Fail()
+		}
+	}
+	res, err := SprintfStrict([]byte{192, 168, 1, 2}, "ip %I %2M")
+	if e, ok := err.(*FormatError); !ok || res != "ip 192.168.1.2 " || e.Problem != "bad width 2" {
+		t.Logf("SprintfStrict: unexpected %q, %v", res, err)
+		t.Fail()
+	}
+	if res, err := SprintfStrict([]byte{1}, "a %2d"); res != "a "+Truncated {
+		t.Logf("SprintfStrict truncated: unexpected %q, %v", res, err)
+		t.Fail()
+	} else if _, ok := err.(*TruncatedError); !ok {
+		t.Logf("SprintfStrict truncated: unexpected %T", err)
+		t.Fail()
+	}
+	if res, err := SprintfStrict([]byte{1}, "a %1.3e"); res != "a "+BadArg+"3" {
+		t.Logf("SprintfStrict bad arg: unexpected %q, %v", res, err)
+		t.Fail()
+	} else if _, ok := err.(*ArgError); !ok {
+		t.Logf("SprintfStrict bad arg: unexpected %T", err)
+		t.Fail()
+	}
+	if res := Sprintf([]byte{1}, "%1d %!"); res != "1 %%UNKOWN%!" {
+		t.Logf("not strict: unexpected %q", res)
+		t.Fail()
+	}
+}
+
+func TestStopOnError(t *testing.T) {
+	var tests = []struct {
+		buf     []byte
+		fmt     string
+		expect  string
+		problem string
+	}{
+		{[]byte{1, 2}, "%1d %1d", "1 2", ""},
+		{[]byte{1, 2}, "%1d %! %1d", "1 %%UNKOWN%!", "unknown verb !"},
+		{[]byte{1, 2, 3, 4, 5, 6}, "%6I %1d", "%%BADWIDTH%6", "bad width 6"},
+		{[]byte{1, 2}, "%1d %4.", "1 %%NOVERB%%4.", "incomplete format"},
+		{[]byte{1, 2}, "%1d %(bits", "1 %%UNKOWN%(bits", "missing )"},
+		{[]byte{1}, "%2d", "%%EOF%", ""},
+	}
+	d := NewDumper()
+	d.StopOnError = true
+	for _, tt := range tests {
+		var b bytes.Buffer
+		_, err := d.Fprintf(&b, tt.buf, tt.fmt)
+		res := b.String()
+		e, ok := err.(*FormatError)
+		if res != tt.expect || (tt.problem != "" && (!ok || e.Problem != tt.problem)) || (tt.problem == "" && ok) {
+			t.Logf("format %q: expected %q and %q, got %q and %v", tt.fmt, tt.expect, tt.problem, res, err)
+			t.Fail()
+		}
+	}
+	if res := d.Sprintf([]byte{1}, "%! %1d"); res != "%%UNKOWN%!" {
+		t.Logf("Sprintf: unexpected %q", res)
+		t.Fail()
+	}
+}
@@ -0,0 +1,219 @@
+package bytefmt
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// writePadded prints s, padded with spaces or cut to prec runes if the
+// _ flag and prec are given.
+func (d *Dumper) writePadded(s string) {
+	if !d.pad || !d.precValid {
+		d.writeString(s)
+		return
+	}
+	n := 0
+	for i := range s {
+		if n == d.prec {
+			s = s[:i]
+			break
+		}
+		n++
+	}
+	d.buf.WriteString(s)
+	for ; n < d.prec; n++ {
+		d.buf.WriteRune(' ')
+	}
+}
+
+// fmtCString prints a NUL terminated string.
+func (d *Dumper) fmtCString() {
+	d.writePadded(string(d.fetchCString()))
+}
+
+// fetchCString consumes a NUL terminated string. With a width, it
+// consumes a field of that many bytes and returns it up to the first
+// NUL. Without one, it consumes up to and including the first NUL, or to
+// the end of the input.
+func (d *Dumper) fetchCString() []byte {
+	if d.widthValid {
+		b := d.fetchBytes(d.width)
+		if i := bytes.IndexByte(b, 0); i >= 0 {
+			b = b[:i]
+		}
+		return b
+	}
+	d.alignByte()
+	start := d.ii
+	for d.fill(d.ii + 1) {
+		d.ii++
+		if d.input[d.ii-1] == 0 {
+			return d.input[start : d.ii-1]
+		}
+	}
+	return d.input[start:d.ii]
+}
+
+// printable returns b with bytes outside of printable ASCII as '.'.
+func printable(b []byte) string {
+	p := append([]byte(nil), b...)
+	for i, c := range p {
+		if c < 0x20 || c > 0x7e {
+			p[i] = '.'
+		}
+	}
+	return string(p)
+}
+
+// fmtFourCC prints a four character code of width (default 4) bytes as
+// by %a.
+func (d *Dumper) fmtFourCC(a []interface{}) {
+	if !d.widthValid {
+		d.width = 4
+	}
+	d.writePadded(printable(d.fetchBytes(d.width)))
+}
+
+// cEscapes holds the C escape sequences for the bytes that have one.
+var cEscapes = map[byte]string{
+	'\a': `\a`, '\b': `\b`, '\f': `\f`, '\n': `\n`, '\r': `\r`,
+	'\t': `\t`, '\v': `\v`, '\\': `\\`, '"': `\"`,
+}
+
+// cQuote returns b as a double quoted C string literal. Bytes outside of
+// printable ASCII without a C escape are printed as \xHH, and the string
+// is split after one if a hex digit follows, as C would otherwise read
+// it as part of the escape.
+func cQuote(b []byte) string {
+	var s strings.Builder
+	s.WriteByte('"')
+	hexEsc := false
+	for _, c := range b {
+		if e, ok := cEscapes[c]; ok {
+			s.WriteString(e)
+			hexEsc = false
+			continue
+		}
+		if c < 0x20 || c > 0x7e {
+			s.WriteString(`\x`)
+			s.WriteByte(hexDigits[c>>4])
+			s.WriteByte(hexDigits[c&0xf])
+			hexEsc = true
+			continue
+		}
+		if hexEsc && strings.IndexByte(hexDigits+"ABCDEF", c) >= 0 {
+			s.WriteString(`""`)
+		}
+		s.WriteByte(c)
+		hexEsc = false
+	}
+	s.WriteByte('"')
+	return s.String()
+}
+
+// fmtRunes prints width (default 1) UTF-8 encoded runes, consuming as
+// many bytes as each one is long. An invalid encoding is printed as
+// utf8.RuneError and consumes one byte.
+func (d *Dumper) fmtRunes() {
+	if !d.widthValid {
+		d.width = 1
+	}
+	for n := 0; n < d.width; n++ {
+		r, _ := utf8.DecodeRune(d.fetchRunes(1))
+		d.buf.WriteRune(r)
+	}
+}
+
+// fetchRunes consumes n UTF-8 encoded runes, an invalid encoding being
+// a single byte.
+func (d *Dumper) fetchRunes(n int) []byte {
+	d.alignByte()
+	start := d.ii
+	for ; n > 0; n-- {
+		d.truncated(1)
+		// Read a byte at a time, so that no bytes past the rune
+		// are taken from a reader.
+		for !utf8.FullRune(d.input[d.ii:]) && d.fill(len(d.input)+1) {
+		}
+		_, size := utf8.DecodeRune(d.input[d.ii:])
+		d.ii += size
+	}
+	return d.input[start:d.ii]
+}
+
+// fmtASCIINums prints a delimited list of ASCII decimal numbers taking
+// width bytes (default the rest of the input). The delimiter is the
+// string argument selected by prec, a comma by default.
+func (d *Dumper) fmtASCIINums(a []interface{}) {
+	if !d.widthValid {
+		d.width = d.remaining()
+	}
+	sep := ","
+	if d.precValid {
+		sep = d.argString(a, d.prec)
+	}
+	for i, tok := range strings.Split(string(d.fetchBytes(d.width)), sep) {
+		if i > 0 {
+			d.buf.WriteString(d.sep())
+		}
+		x, err := strconv.ParseInt(strings.TrimSpace(tok), 10, 64)
+		if err != nil {
+			d.badValue(tok)
+			continue
+		}
+		d.buf.WriteString(strconv.FormatInt(x, 10))
+	}
+}
+
+// fmtASCIIInt prints the ASCII number in a field of width (default the
+// rest) bytes in decimal. Surrounding spaces are ignored, the digits are
+// decimal or with the # flag hex.
+func (d *Dumper) fmtASCIIInt(a []interface{}) {
+	if !d.widthValid {
+		d.width = d.remaining()
+	}
+	base := 10
+	if d.altFlag {
+		base = 16
+	}
+	f := string(d.fetchBytes(d.width))
+	x, err := strconv.ParseInt(strings.TrimSpace(f), base, 64)
+	if err != nil {
+		d.badValue(f)
+		return
+	}
+	d.writeDecimal(strconv.FormatInt(x, 10))
+}
+
+// fmtUTF16BOM prints a UTF-16 string prefixed by its length in bytes
+// (width, default 2). A leading byte order mark selects the byte order
+// of the string and is dropped, without one the intel flag applies.
+func (d *Dumper) fmtUTF16BOM(a []interface{}) {
+	if !d.widthValid {
+		d.width = 2
+	}
+	b := d.fetchPrefixed()
+	intel := d.intel
+	if len(b) >= 2 {
+		switch {
+		case b[0] == 0xfe && b[1] == 0xff:
+			intel = false
+			b = b[2:]
+		case b[0] == 0xff && b[1] == 0xfe:
+			intel = true
+			b = b[2:]
+		}
+	}
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		if intel {
+			u[i] = uint16(b[2*i]) | uint16(b[2*i+1])<<8
+		} else {
+			u[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+		}
+	}
+	d.buf.WriteString(string(utf16.Decode(u)))
+}
@@ -0,0 +1,234 @@
+package bytefmt
+
+import (
+	"testing"
+)
+
+func TestASCIINums(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte("12,34,56"), "%(asciinums)", "12, 34, 56"},
+		{[]byte("007, -8,9\x01"), "%9(asciinums) %1x", "7, -8, 9 1"},
+		{[]byte("1;x;3"), "%.0(asciinums)", "1, %%BADVALUE%x, 3"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt, ";")
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestASCIIInt(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte("000042"), "%6(asciiint)", "42"},
+		{[]byte("   -17"), "%(asciiint)", "-17"},
+		{[]byte("12  \x01"), "%4(asciiint) %1x", "12 1"},
+		{[]byte("1a2f\r\n"), "%#4(asciiint)%2z", "6703"},
+		{[]byte("1000000"), "%,(asciiint)", "1,000,000"},
+		{[]byte("12x4"), "%4(asciiint)", "%%BADVALUE%12x4"},
+		{[]byte("    "), "%4(asciiint)", "%%BADVALUE%    "},
+		{[]byte("12"), "%4(asciiint)", "%%EOF%"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestUTF16BOM(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x00, 0x08, 0xff, 0xfe, 'H', 0, 'i', 0, 0xac, 0x20}, "%(utf16bom)", "Hi€"},
+		{[]byte{0x00, 0x08, 0xfe, 0xff, 0, 'H', 0, 'i', 0x20, 0xac}, "%(utf16bom)", "Hi€"},
+		{[]byte{0x04, 0xfe, 0xff, 0, 'H'}, "%1(utf16bom)", "H"},
+		{[]byte{0x04, 0x00, 'H', 0, 'i', 0}, "%-2(utf16bom)", "Hi"},
+		{[]byte{0x04, 0, 'H', 0, 'i'}, "%1(utf16bom)", "Hi"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestRunes(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte("a"), "%c", "a"},
+		{[]byte("äb"), "%c%1d", "ä98"},
+		{[]byte("日本語"), "%3c", "日本語"},
+		{[]byte("\xf0\x9f\x98\x80!"), "%c %s", "😀 !"},
+		{[]byte{0xff, 'x'}, "%2c", "�x"},
+		{[]byte{0xe6, 0x97}, "%c%1d", "�151"},
+		{[]byte("a"), "%2c", "a%%EOF%"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestQuoteRunes(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte("日本語abc"), "%#3q %s", `"日本語" abc`},
+		{[]byte("日本語abc"), "%3q %s", `"日" 本語abc`},
+		{[]byte("äb"), "%#1q%1d", `"ä"98`},
+		{[]byte{0xff, 'x', 'y'}, "%#2q%s", `"\xffx"y`},
+		{[]byte("äb"), "%#q", `"äb"`},
+		{[]byte("äb"), "%#0q%s", `""äb`},
+		{[]byte("ä"), "%#2q", "%%EOF%"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestPadded(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte("abc"), "[%_3.6s]", "[abc   ]"},
+		{[]byte("abcdef"), "[%_.3s]", "[abc]"},
+		{[]byte("äöü!"), "[%_.2s]", "[äö]"},
+		{[]byte("ab"), "[%_2.5q]", `["ab" ]`},
+		{[]byte("a\x00"), "[%_2.3a]", "[a. ]"},
+		{[]byte("abc"), "[%_.0s]", "[]"},
+		{[]byte("abc"), "[%_s]", "[abc]"},
+		{[]byte("abcd"), "[%.1s]", "[abc]"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestPrefixedString(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte("\x03abcd"), "%#s %1x", "abc 64"},
+		{[]byte("\x00\x02hi"), "%#2s", "hi"},
+		{[]byte("\x02\x00hi"), "%#-2s", "hi"},
+		{[]byte("\x00"), "%#s", ""},
+		{[]byte("\x02hi\x01x"), "%#s*2", "hi, x"},
+		{[]byte("\x02hi"), "%_#.4s|", "hi  |"},
+		{[]byte("\x05hi"), "%#s", "%%EOF%"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestCString(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte("abc\x00def\x00"), "%Z|%Z", "abc|def"},
+		{[]byte("ab\x00\x00\x00\x00\x07"), "%6Z %1d", "ab 7"},
+		{[]byte("abcdef\x07"), "%6Z %1d", "abcdef 7"},
+		{[]byte("ab\x00cd\x00\x07"), "%6Z %1d", "ab 7"},
+		{[]byte("abc"), "%Z", "abc"},
+		{[]byte("\x00\x01"), "%Z%1d", "1"},
+		{[]byte("ab\x00"), "%4Z", "%%EOF%"},
+		{[]byte("ab\x00"), "%_.4Z|", "ab  |"},
+		{[]byte("a\x00b\x00"), "%Z*2", "a, b"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestCQuote(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte("abc"), "%C", `"abc"`},
+		{[]byte("a\tb\n"), "%C", `"a\tb\n"`},
+		{[]byte("\"\\\a\b\f\r\v"), "%C", `"\"\\\a\b\f\r\v"`},
+		{[]byte{0x00, 0x7f, 0x80, 0xff}, "%C", `"\x00\x7f\x80\xff"`},
+		{[]byte("\x01abc\x02g\x03F"), "%C", `"\x01""abc\x02g\x03""F"`},
+		{[]byte("héllo"), "%C", `"h\xc3\xa9llo"`},
+		{[]byte("abcdef"), "%3C|%C", `"abc"|"def"`},
+		{[]byte("abc1234"), "%.4C", `"abc"`},
+		{[]byte("ab"), "%_.5C|", `"ab" |`},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestFourCC(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte("RIFF\x24\x00\x00\x00"), "%(fourcc) %-4d", "RIFF 36"},
+		{[]byte("OTTO"), "%(fourcc)", "OTTO"},
+		{[]byte("ab\x00\xff"), "%(fourcc)", "ab.."},
+		{[]byte("mvhdx"), "%5(fourcc)", "mvhdx"},
+		{[]byte("ftyp"), "[%_.6(fourcc)]", "[ftyp  ]"},
+		{[]byte("ftp"), "%(fourcc)", "%%EOF%"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package bytefmt
+
+import (
+	"strconv"
+	"time"
+)
+
+// writeTime prints t as RFC 3339 in UTC with as many fractional second
+// digits as needed.
+func (d *Dumper) writeTime(t time.Time) {
+	d.buf.WriteString(t.UTC().Format(time.RFC3339Nano))
+}
+
+// fmtUnixTime prints a 4 (default) or 8 byte count of seconds, or
+// milliseconds with the # flag or nanoseconds with the _ flag, since the
+// Unix epoch. If prec is given, it selects a layout string argument.
+func (d *Dumper) fmtUnixTime(a []interface{}) {
+	if !d.widthValid {
+		d.width = 4
+	}
+	if d.width != 4 && d.width != 8 {
+		d.badWidth(d.width)
+		return
+	}
+	x := d.fetchSigned()
+	t := time.Unix(x, 0)
+	switch {
+	case d.pad:
+		t = time.Unix(0, x)
+	case d.altFlag:
+		t = time.Unix(x/1000, x%1000*int64(time.Millisecond))
+	}
+	if d.precValid {
+		d.buf.WriteString(t.UTC().Format(d.argString(a, d.prec)))
+		return
+	}
+	d.writeTime(t)
+}
+
+// filetimeEpoch is the number of seconds from the FILETIME epoch,
+// 1601-01-01, to the Unix epoch.
+const filetimeEpoch = 11644473600
+
+// fmtFiletime prints a Windows FILETIME, an 8 byte count of 100 ns
+// intervals since 1601, which is little endian unless the > flag is
+// given. If prec is given, it selects a layout string argument.
+func (d *Dumper) fmtFiletime(a []interface{}) {
+	if !d.widthValid {
+		d.width = 8
+	}
+	if d.width != 8 {
+		d.badWidth(d.width)
+		return
+	}
+	d.intel = !d.big
+	x := uint64(d.fetchInt())
+	t := time.Unix(int64(x/1e7)-filetimeEpoch, int64(x%1e7*100))
+	if d.precValid {
+		d.buf.WriteString(t.UTC().Format(d.argString(a, d.prec)))
+		return
+	}
+	d.writeTime(t)
+}
+
+// ntpEpoch is the number of seconds from the NTP epoch, 1900-01-01, to
+// the Unix epoch.
+const ntpEpoch = 2208988800
+
+// fmtNTPTime prints an NTP timestamp of width 8 (default), 32 bit
+// seconds since 1900 and a 32 bit binary fraction, each word in the
+// byte order of the flags. The 4 byte NTP short format of 16 bit
+// seconds and fraction is an interval, printed as a time.Duration. If
+// prec is given, it selects a layout string argument for a timestamp.
+func (d *Dumper) fmtNTPTime(a []interface{}) {
+	if !d.widthValid {
+		d.width = 8
+	}
+	if d.width != 4 && d.width != 8 {
+		d.badWidth(d.width)
+		return
+	}
+	bits := uint(4 * d.width)
+	d.width /= 2
+	sec := uint64(d.fetchInt())
+	frac := uint64(d.fetchInt())
+	nsec := int64(frac * uint64(time.Second) >> bits)
+	if bits == 16 {
+		d.buf.WriteString((time.Duration(sec)*time.Second + time.Duration(nsec)).String())
+		return
+	}
+	t := time.Unix(int64(sec)-ntpEpoch, nsec)
+	if d.precValid {
+		d.buf.WriteString(t.UTC().Format(d.argString(a, d.prec)))
+		return
+	}
+	d.writeTime(t)
+}
+
+// durationUnits are the units of %(duration) selected by prec.
+var durationUnits = []time.Duration{time.Nanosecond, time.Microsecond, time.Millisecond, time.Second, time.Minute, time.Hour}
+
+// fmtDuration prints an int of width (default DefaultIntWidth) bytes as
+// a time.Duration, e.g. 1h30m0s. prec selects the unit of the int: 0
+// for nanoseconds, 1 microseconds, 2 milliseconds, 3 seconds (default),
+// 4 minutes and 5 hours.
+func (d *Dumper) fmtDuration(a []interface{}) {
+	d.intWidth()
+	x := d.fetchSigned()
+	unit := time.Second
+	if d.precValid {
+		if d.prec >= len(durationUnits) {
+			d.badValue(strconv.Itoa(d.prec))
+			return
+		}
+		unit = durationUnits[d.prec]
+	}
+	v := time.Duration(x) * unit
+	if v/unit != time.Duration(x) {
+		d.badValue(strconv.FormatInt(x, 10))
+		return
+	}
+	d.buf.WriteString(v.String())
+}
+
+// fmtExpTime prints a timestamp stored as a unit exponent byte followed
+// by a width (default 8) byte count of 10^-exp seconds since the Unix
+// epoch, so 0 gives seconds, 3 milliseconds and 9 nanoseconds.
+func (d *Dumper) fmtExpTime(a []interface{}) {
+	if !d.widthValid {
+		d.width = 8
+	}
+	exp := int(d.fetchBytes(1)[0])
+	x := d.fetchInt()
+	if exp > 9 {
+		d.badValue(strconv.Itoa(exp))
+		return
+	}
+	unit := int64(1)
+	for i := 0; i < exp; i++ {
+		unit *= 10
+	}
+	d.writeTime(time.Unix(x/unit, x%unit*(int64(time.Second)/unit)))
+}
+
+// itoaPad formats x in decimal, zero padded to n digits.
+func itoaPad(x, n int) string {
+	s := strconv.Itoa(x)
+	for len(s) < n {
+		s = "0" + s
+	}
+	return s
+}
+
+// dosDate splits a DOS/FAT date into ISO 8601 notation. The fields are
+// not validated, so the zero value is 1980-00-00.
+func dosDate(v int64) string {
+	return itoaPad(1980+int(v>>9), 4) + "-" + itoaPad(int(v>>5&0xf), 2) + "-" + itoaPad(int(v&0x1f), 2)
+}
+
+// dosTime splits a DOS/FAT time, which counts seconds in units of two,
+// into ISO 8601 notation. Like dosDate, it does not validate the fields.
+func dosTime(v int64) string {
+	return itoaPad(int(v>>11), 2) + ":" + itoaPad(int(v>>5&0x3f), 2) + ":" + itoaPad(2*int(v&0x1f), 2)
+}
+
+// fmtDOSDate prints a 2 (default) byte DOS/FAT date, or with width 4 a
+// time word followed by a date word as in ZIP and FAT directory
+// entries. The byte order applies to each 2 byte word.
+func (d *Dumper) fmtDOSDate(a []interface{}) {
+	if !d.widthValid {
+		d.width = 2
+	}
+	switch d.width {
+	case 2:
+		d.buf.WriteString(dosDate(d.fetchInt()))
+	case 4:
+		d.width = 2
+		t := d.fetchInt()
+		d.buf.WriteString(dosDate(d.fetchInt()) + "T" + dosTime(t))
+	default:
+		d.badWidth(d.width)
+	}
+}
+
+// fmtTAITime prints a width (default 8) byte count of TAI seconds since
+// the Unix epoch as UTC, subtracting the int leap second offset argument
+// selected by prec.
+func (d *Dumper) fmtTAITime(a []interface{}) {
+	if !d.widthValid {
+		d.width = 8
+	}
+	x := d.fetchInt()
+	if d.precValid {
+		x -= int64(d.argInt(a, d.prec))
+	}
+	d.writeTime(time.Unix(x, 0))
+}
+
+// generalizedTime is the layout of an ASN.1 GeneralizedTime without
+// fractional seconds.
+const generalizedTime = "20060102150405Z0700"
+
+// fmtASCIITime prints a timestamp stored as width (default 15) ASCII
+// bytes, parsed with the layout string argument selected by prec or as a
+// GeneralizedTime. A timestamp that does not parse is quoted after
+// BadValue.
+func (d *Dumper) fmtASCIITime(a []interface{}) {
+	if !d.widthValid {
+		d.width = len("20060102150405Z")
+	}
+	layout := generalizedTime
+	if d.precValid {
+		layout = d.argString(a, d.prec)
+	}
+	b := d.fetchBytes(d.width)
+	t, err := time.Parse(layout, string(b))
+	if err != nil {
+		d.badValue(strconv.Quote(string(b)))
+		return
+	}
+	d.writeTime(t)
+}
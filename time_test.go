@@ -0,0 +1,197 @@
+package bytefmt
+
+import (
+	"testing"
+)
+
+func TestExpTime(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0, 0x65, 0x92, 0x00, 0x80}, "%4(exptime)", "2024-01-01T00:00:00Z"},
+		{[]byte{9, 0x17, 0xa6, 0x10, 0x17, 0x08, 0xc0, 0xcd, 0x15}, "%(exptime)", "2024-01-01T00:00:00.123456789Z"},
+		{[]byte{3, 0xe8, 0x03}, "%-2(exptime)", "1970-01-01T00:00:01Z"},
+		{[]byte{12, 0x00, 0x01}, "%2(exptime)", "%%BADVALUE%12"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestDOSDate(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x58, 0x3f}, "%(dosdate)", "2024-01-31"},
+		{[]byte{0x3f, 0x58}, "%-(dosdate)", "2024-01-31"},
+		{[]byte{0x00, 0x21}, "%(dosdate)", "1980-01-01"},
+		{[]byte{0x00, 0x00}, "%(dosdate)", "1980-00-00"},
+		{[]byte{0x7d, 0x1e, 0x58, 0x3f}, "%4(dosdate)", "2024-01-31T15:40:60"},
+		{[]byte{0x7c, 0x7b, 0x58, 0x3f}, "%4(dosdate)", "2024-01-31T15:35:54"},
+		{[]byte{0x7b, 0x7c, 0x3f, 0x58}, "%-4(dosdate)", "2024-01-31T15:35:54"},
+		{[]byte{0x00, 0x00, 0x00, 0x21}, "%4(dosdate)", "1980-01-01T00:00:00"},
+		{[]byte{0x58, 0x3f, 0x00}, "%3(dosdate)", "%%BADWIDTH%3"},
+		{[]byte{0x58, 0x3f}, "%4(dosdate)", "%%EOF%"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestTAITime(t *testing.T) {
+	// 2017-01-01T00:00:00Z is 1483228800 in UTC, 37 leap seconds later in TAI.
+	buf := []byte{0x00, 0x00, 0x00, 0x00, 0x58, 0x68, 0x46, 0xa5}
+	res := Sprintf(buf, "%.0(taitime)", 37)
+	expected := "2017-01-01T00:00:00Z"
+	if res != expected {
+		t.Logf("taitime expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf(buf, "%(taitime)")
+	expected = "2017-01-01T00:00:37Z"
+	if res != expected {
+		t.Logf("taitime expected %q, res %q", expected, res)
+		t.Fail()
+	}
+	res = Sprintf([]byte{0xa5, 0x46, 0x68, 0x58}, "%-4.0(taitime)", 37)
+	expected = "2017-01-01T00:00:00Z"
+	if res != expected {
+		t.Logf("taitime expected %q, res %q", expected, res)
+		t.Fail()
+	}
+}
+
+func TestUnixTime(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x65, 0x53, 0xf1, 0x00}, "%T", "2023-11-14T22:13:20Z"},
+		{[]byte{0x00, 0xf1, 0x53, 0x65}, "%-4T", "2023-11-14T22:13:20Z"},
+		{[]byte{0, 0, 0, 0, 0x65, 0x53, 0xf1, 0x00}, "%8T", "2023-11-14T22:13:20Z"},
+		{[]byte{0, 0, 0x01, 0x8b, 0xcf, 0xe5, 0x68, 0x7b}, "%#8T", "2023-11-14T22:13:20.123Z"},
+		{[]byte{0x17, 0x97, 0x9c, 0xfe, 0x3d, 0x85, 0xcd, 0x15}, "%_8T", "2023-11-14T22:13:20.123456789Z"},
+		{[]byte{0x17, 0x97, 0x9c, 0xfe, 0x3d, 0x85, 0xcd, 0x15}, "%_#8T", "2023-11-14T22:13:20.123456789Z"},
+		{[]byte{0xff, 0xff, 0xff, 0xff}, "%T", "2106-02-07T06:28:15Z"},
+		{[]byte{0xff, 0xff, 0xff, 0xff}, "%+T", "1969-12-31T23:59:59Z"},
+		{[]byte{0x65, 0x53, 0xf1, 0x00}, "%.0T", "2023-11-14"},
+		{[]byte{0x65, 0x53, 0xf1, 0x00}, "%2T", "%%BADWIDTH%2"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt, "2006-01-02")
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestASCIITime(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte("20240131123000Z"), "%(asciitime)", "2024-01-31T12:30:00Z"},
+		{[]byte("20240131123000+0100"), "%19(asciitime)", "2024-01-31T11:30:00Z"},
+		{[]byte("240131123000Z|"), "%13.0(asciitime)%1s", "2024-01-31T12:30:00Z|"},
+		{[]byte("2024-01-31"), "%10.1(asciitime)", "2024-01-31T00:00:00Z"},
+		{[]byte("20241331123000Z"), "%(asciitime)", "%%BADVALUE%\"20241331123000Z\""},
+		{[]byte("2024"), "%(asciitime)", "%%EOF%"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt, "060102150405Z", "2006-01-02")
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestFiletime(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0x00, 0x80, 0x3e, 0xd5, 0xde, 0xb1, 0x9d, 0x01}, "%(filetime)", "1970-01-01T00:00:00Z"},
+		{[]byte{0x01, 0x9d, 0xb1, 0xde, 0xd5, 0x3e, 0x80, 0x00}, "%>(filetime)", "1970-01-01T00:00:00Z"},
+		{[]byte{0x87, 0x96, 0x9c, 0x76, 0x45, 0x3c, 0xda, 0x01}, "%8(filetime)", "2024-01-01T00:00:00.1234567Z"},
+		{[]byte{0x87, 0x96, 0x9c, 0x76, 0x45, 0x3c, 0xda, 0x01}, "%.0(filetime)", "2024-01-01"},
+		{make([]byte, 8), "%(filetime)", "1601-01-01T00:00:00Z"},
+		{make([]byte, 4), "%4(filetime)", "%%BADWIDTH%4"},
+		{make([]byte, 4), "%(filetime)", "%%EOF%"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt, "2006-01-02")
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestNTPTime(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0xe9, 0x3c, 0x7f, 0x00, 0x80, 0x00, 0x00, 0x00}, "%(ntp)", "2024-01-01T00:00:00.5Z"},
+		{[]byte{0x00, 0x7f, 0x3c, 0xe9, 0x00, 0x00, 0x00, 0x40}, "%-(ntp)", "2024-01-01T00:00:00.25Z"},
+		{[]byte{0x83, 0xaa, 0x7e, 0x80, 0x00, 0x00, 0x00, 0x00}, "%8(ntp)", "1970-01-01T00:00:00Z"},
+		{[]byte{0xe9, 0x3c, 0x7f, 0x00, 0x00, 0x00, 0x00, 0x00}, "%.0(ntp)", "2024-01-01"},
+		{[]byte{0x00, 0x01, 0x80, 0x00}, "%4(ntp)", "1.5s"},
+		{[]byte{0x00, 0x00, 0x00, 0x01}, "%4(ntp)", "15.258µs"},
+		{make([]byte, 2), "%2(ntp)", "%%BADWIDTH%2"},
+		{make([]byte, 4), "%(ntp)", "%%EOF%"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt, "2006-01-02")
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestDuration(t *testing.T) {
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{[]byte{0, 0, 0x15, 0x18}, "%(duration)", "1h30m0s"},
+		{[]byte{0x18, 0x15}, "%-2(duration)", "1h30m0s"},
+		{[]byte{0x05, 0xdc}, "%2.2(duration)", "1.5s"},
+		{[]byte{0x03, 0xe8}, "%2.0(duration)", "1µs"},
+		{[]byte{0x03, 0xe8}, "%2.1(duration)", "1ms"},
+		{[]byte{0x5a}, "%1.4(duration)", "1h30m0s"},
+		{[]byte{0x02}, "%1.5(duration)", "2h0m0s"},
+		{[]byte{0xff, 0xc4}, "%+2(duration)", "-1m0s"},
+		{[]byte{0xff, 0xc4}, "%2(duration)", "18h11m16s"},
+		{[]byte{0}, "%1(duration)", "0s"},
+		{[]byte{1}, "%1.6(duration)", "%%BADVALUE%6"},
+		{[]byte{0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, "%8.5(duration)", "%%BADVALUE%9223372036854775807"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
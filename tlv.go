@@ -0,0 +1,62 @@
+package bytefmt
+
+// DumpTLV formats buf as a sequence of type-length-value triples, one
+// per line. The type and the length are printed with typeFmt and lenFmt,
+// whose consumed bytes are read as an unsigned int in the byte order of
+// their last format (e.g. %1v or %-2x). The value of that many bytes is
+// then printed with the format for the type in bodyFmts, or in hex as by
+// %h for unknown types. A length that overruns buf stops formatting
+// with the Truncated marker and a *TruncatedError.
+func DumpTLV(buf []byte, typeFmt, lenFmt string, bodyFmts map[int64]string) (string, error) {
+	d := getDumper()
+	s, err := d.DumpTLV(buf, typeFmt, lenFmt, bodyFmts)
+	putDumper(d)
+	return s, err
+}
+
+// DumpTLV is like the package level DumpTLV, reusing d.
+func (d *Dumper) DumpTLV(buf []byte, typeFmt, lenFmt string, bodyFmts map[int64]string) (s string, err error) {
+	d.Reset()
+	d.setInput(buf)
+	defer func() {
+		if e := recover(); e != nil {
+			err = d.stopped(e)
+		}
+		s = d.buf.String()
+	}()
+	for d.available(1) {
+		start := d.ii
+		typ := d.tlvField(typeFmt)
+		d.buf.WriteRune(' ')
+		n := d.tlvField(lenFmt)
+		d.buf.WriteRune(' ')
+		b := d.fetchBytes(int(n))
+		if f, ok := bodyFmts[typ]; ok {
+			d.dumpRegion(b, f, nil)
+		} else {
+			d.dumpRegion(b, "%h", nil)
+		}
+		d.buf.WriteRune('\n')
+		if d.ii == start {
+			// Fields consuming nothing would never reach the end.
+			break
+		}
+	}
+	return
+}
+
+// tlvField prints the field format f and returns the bytes it consumed
+// as an unsigned int.
+func (d *Dumper) tlvField(f string) int64 {
+	start := d.ii
+	d.doDump(f, nil)
+	d.alignByte()
+	if start > d.ii {
+		start = d.ii
+	}
+	b := d.input[start:d.ii]
+	if len(b) > 8 {
+		panic(&WidthError{Verb: d.verb, Width: len(b)})
+	}
+	return decodeInt(b, d.intel)
+}
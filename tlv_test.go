@@ -0,0 +1,42 @@
+package bytefmt
+
+import (
+	"testing"
+)
+
+func TestDumpTLV(t *testing.T) {
+	bodies := map[int64]string{
+		1: "name=%s",
+		2: "port=%2d",
+	}
+	var tests = []struct {
+		buf     []byte
+		typeFmt string
+		lenFmt  string
+		expect  string
+		err     bool
+	}{
+		{[]byte("\x01\x02hi\x02\x02\x00\x50"), "%1v", "%1v", "1 2 name=hi\n2 2 port=80\n", false},
+		{[]byte("\x07\x03\xde\xad\xbe"), "t=%1x", "l=%1v", "t=7 l=3 de:ad:be\n", false},
+		{[]byte("\x01\x02\x00hi"), "%1v", "%-2v", "1 2 name=hi\n", false},
+		{[]byte("\x00\x02\x00\x02\x00\x16"), "%2v", "%2v", "2 2 port=22\n", false},
+		{[]byte{}, "%1v", "%1v", "", false},
+		{[]byte("\x01\x05hi"), "%1v", "%1v", "1 5 %%EOF%", true},
+		{[]byte("\x01"), "%1v", "%1v", "1 %%EOF%", true},
+		{[]byte("\x01"), "t", "%0v", "t 0 \n", false},
+	}
+	for _, tt := range tests {
+		res, err := DumpTLV(tt.buf, tt.typeFmt, tt.lenFmt, bodies)
+		if res != tt.expect || (err != nil) != tt.err {
+			t.Logf("buf %q: expected %q, res %q, err %v", tt.buf, tt.expect, res, err)
+			t.Fail()
+		}
+	}
+	if _, err := DumpTLV([]byte("\x01\x05hi"), "%1v", "%1v", bodies); err == nil {
+		t.Logf("overrun: no error")
+		t.Fail()
+	} else if _, ok := err.(*TruncatedError); !ok {
+		t.Logf("overrun: unexpected error %v", err)
+		t.Fail()
+	}
+}
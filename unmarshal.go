@@ -0,0 +1,178 @@
+package bytefmt
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// Unmarshal decodes buf into the struct pointed to by v. Each field
+// with a bytefmt tag consumes input in declaration order, the tag being
+// a format without its leading % (e.g. `bytefmt:"-2d"`). Int fields take
+// %d, %v, %x, %o, %b, %w and %W, float fields %f, string and []byte
+// fields %s, %a and %Z. A %z or %@ tag moves through the input on any
+// field, including _. Struct fields tagged "" are decoded recursively.
+// An int is signed only with the + flag, so an unsigned one above
+// math.MaxInt64 does not fit an int64 field, nor a negative one a uint
+// field. A read past the end of buf returns a *TruncatedError, any other
+// problem a *FormatError.
+func Unmarshal(buf []byte, v interface{}) error {
+	d := getDumper()
+	err := d.Unmarshal(buf, v)
+	putDumper(d)
+	return err
+}
+
+// Unmarshal is like the package level Unmarshal, reusing d.
+func (d *Dumper) Unmarshal(buf []byte, v interface{}) (err error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return &FormatError{Problem: "Unmarshal needs a pointer to a struct"}
+	}
+	d.Reset()
+	d.setInput(buf)
+	strict := d.Strict
+	d.Strict = true
+	defer func() {
+		d.Strict = strict
+		if e := recover(); e != nil {
+			err = d.stopped(e)
+		}
+	}()
+	d.unmarshalStruct(rv.Elem())
+	return nil
+}
+
+// unmarshalStruct decodes the tagged fields of the struct v.
+func (d *Dumper) unmarshalStruct(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup("bytefmt")
+		if !ok || tag == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		if tag == "" && f.Type.Kind() == reflect.Struct {
+			d.unmarshalStruct(fv)
+			continue
+		}
+		c, _, _, _, problem := d.spec.parse("%"+tag, 0)
+		if problem == "" && c == '(' {
+			problem = "named verbs are not supported"
+		}
+		if problem == "" && d.widthArg {
+			// There are no arguments to take the width from.
+			problem = "width arguments are not supported"
+		}
+		if problem != "" {
+			d.verb = "%" + tag
+			d.fail(f.Name + ": " + problem)
+		}
+		switch c {
+		case 'z':
+			if !d.widthValid {
+				d.width = 1
+			}
+			d.fetchBytes(d.width)
+			continue
+		case '@':
+			d.seek(d.width)
+			continue
+		}
+		if !fv.CanSet() || !d.unmarshalField(c, fv) {
+			d.fail("cannot decode " + f.Name + " of type " + f.Type.String())
+		}
+	}
+}
+
+// unmarshalField decodes the format with verb letter c into v. It
+// returns false if the type of v does not suit the format.
+func (d *Dumper) unmarshalField(c byte, v reflect.Value) bool {
+	switch c {
+	case 'd', 'v', 'x', 'o', 'b':
+		var x int64
+		bits, slot, bad := d.intSlot(c)
+		switch {
+		case bits:
+			x = int64(d.fetchBits(d.prec))
+			if d.signed && d.prec > 0 {
+				shift := uint(64 - d.prec)
+				x = x << shift >> shift
+			}
+		case c == 'v':
+			if !d.widthValid {
+				d.width = 1
+			}
+			x = d.fetchInt()
+		case bad:
+			d.badWidth(d.prec)
+		default:
+			d.intWidth()
+			x = d.fetchSlot(slot)
+		}
+		return d.setInt(v, x, d.signed)
+	case 'w', 'W':
+		x, ok := d.fetchVarint()
+		if !ok {
+			d.badValue("")
+		}
+		if c == 'W' {
+			return d.setInt(v, int64(x>>1)^-int64(x&1), true)
+		}
+		return d.setInt(v, int64(x), false)
+	case 'f':
+		x, _ := d.fetchFloat()
+		if k := v.Kind(); k != reflect.Float32 && k != reflect.Float64 {
+			return false
+		}
+		v.SetFloat(x)
+		return true
+	case 's', 'a', 'Z':
+		var b []byte
+		if c == 'Z' {
+			b = d.fetchCString()
+		} else {
+			d.restWidth()
+			b = d.fetchBytes(d.width)
+		}
+		switch {
+		case v.Kind() == reflect.String:
+			v.SetString(string(b))
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+			v.SetBytes(append([]byte(nil), b...))
+		default:
+			return false
+		}
+		return true
+	}
+	d.fail("unsupported verb " + string(c))
+	return false
+}
+
+// setInt stores x, a signed int if signed is set and an unsigned one
+// otherwise, in the int or uint v. It returns false if v is not an int
+// or x does not fit. An unsigned x above math.MaxInt64 for an int v and
+// a negative x for a uint v are flagged with BadValue.
+func (d *Dumper) setInt(v reflect.Value, x int64, signed bool) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !signed && x < 0 {
+			d.badValue(strconv.FormatUint(uint64(x), 10))
+		}
+		if v.OverflowInt(x) {
+			return false
+		}
+		v.SetInt(x)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if signed && x < 0 {
+			d.badValue(strconv.FormatInt(x, 10))
+		}
+		if v.OverflowUint(uint64(x)) {
+			return false
+		}
+		v.SetUint(uint64(x))
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package bytefmt
+
+import (
+	"bytes"
+	"testing"
+)
+
+type testHeader struct {
+	Magic   string  `bytefmt:"4s"`
+	Version uint16  `bytefmt:"-2d"`
+	Flags   uint8   `bytefmt:"1x"`
+	_       [0]byte `bytefmt:"1z"`
+	Offset  int32   `bytefmt:"+4d"`
+	Kind    int     `bytefmt:".4d"`
+	Level   int     `bytefmt:".4d"`
+	Name    string  `bytefmt:"Z"`
+	Scale   float32 `bytefmt:"f"`
+	Skipped int
+	Nested  struct {
+		Len  uint64 `bytefmt:"w"`
+		Body []byte `bytefmt:"2s"`
+	} `bytefmt:""`
+	Rest []byte `bytefmt:"s"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	buf := []byte("RIFF\x02\x01\x80\xff\xff\xff\xff\xfe\x3aab\x00\x3f\xc0\x00\x00\xac\x02hi!")
+	var h testHeader
+	if err := Unmarshal(buf, &h); err != nil {
+		t.Logf("unexpected error %v", err)
+		t.Fail()
+	}
+	if h.Magic != "RIFF" || h.Version != 0x0102 || h.Flags != 0x80 || h.Offset != -2 ||
+		h.Kind != 3 || h.Level != 10 || h.Name != "ab" || h.Scale != 1.5 ||
+		h.Nested.Len != 300 || string(h.Nested.Body) != "hi" || !bytes.Equal(h.Rest, []byte("!")) {
+		t.Logf("unexpected result %+v", h)
+		t.Fail()
+	}
+	err := Unmarshal(buf[:10], &h)
+	if _, ok := err.(*TruncatedError); !ok {
+		t.Logf("short buffer: unexpected error %v", err)
+		t.Fail()
+	}
+	var bad struct {
+		N string `bytefmt:"2d"`
+	}
+	err = Unmarshal([]byte{1, 2}, &bad)
+	if e, ok := err.(*FormatError); !ok || e.Problem != "cannot decode N of type string" || e.Verb != "%2d" {
+		t.Logf("bad type: unexpected error %v", err)
+		t.Fail()
+	}
+	var small struct {
+		N int8 `bytefmt:"2d"`
+	}
+	if err := Unmarshal([]byte{1, 2}, &small); err == nil {
+		t.Logf("overflow: no error")
+		t.Fail()
+	}
+	var named struct {
+		N int `bytefmt:"(bits)"`
+	}
+	if err := Unmarshal([]byte{1}, &named); err == nil {
+		t.Logf("named verb: no error")
+		t.Fail()
+	}
+	var big struct {
+		N int64 `bytefmt:"8d"`
+	}
+	ones := []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	if e, ok := Unmarshal(ones, &big).(*FormatError); !ok || e.Problem != "bad value 18446744073709551615" {
+		t.Logf("unsigned into int64: unexpected error %v", e)
+		t.Fail()
+	}
+	var neg struct {
+		N uint64 `bytefmt:"+8d"`
+	}
+	if e, ok := Unmarshal(ones, &neg).(*FormatError); !ok || e.Problem != "bad value -1" {
+		t.Logf("negative into uint64: unexpected error %v", e)
+		t.Fail()
+	}
+	var star struct {
+		S string `bytefmt:"*s"`
+		N int    `bytefmt:"1d"`
+	}
+	if e, ok := Unmarshal([]byte("a\x05"), &star).(*FormatError); !ok || e.Problem != "S: width arguments are not supported" {
+		t.Logf("* width: unexpected error %v", e)
+		t.Fail()
+	}
+	var width struct {
+		F float64 `bytefmt:"3f"`
+	}
+	if e, ok := Unmarshal([]byte{1, 2, 3}, &width).(*FormatError); !ok || e.Problem != "bad width 3" {
+		t.Logf("bad width: unexpected error %v", e)
+		t.Fail()
+	}
+	var slot struct {
+		N int `bytefmt:"2.1d"`
+		B int `bytefmt:"1.4d"`
+	}
+	if e, ok := Unmarshal([]byte{0xff, 0x05, 0xf3}, &slot).(*FormatError); !ok || slot.N != 5 || e.Problem != "bad width 4" {
+		t.Logf("slot: unexpected %+v, error %v", slot, e)
+		t.Fail()
+	}
+	if err := Unmarshal(buf, h); err == nil {
+		t.Logf("non pointer: no error")
+		t.Fail()
+	}
+}
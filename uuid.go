@@ -0,0 +1,41 @@
+package bytefmt
+
+import (
+	"encoding/hex"
+)
+
+// fmtUUID prints a 16 byte RFC 4122 UUID as 8-4-4-4-12 lowercase hex
+// digits. With the # flag, the first three fields are read little
+// endian, as in a Microsoft GUID. Other widths than 16 are flagged with
+// BadWidth and skipped.
+func (d *Dumper) fmtUUID() {
+	if !d.widthValid {
+		d.width = 16
+	}
+	if d.width != 16 {
+		d.badWidth(d.width)
+		d.fetchBytes(d.width)
+		return
+	}
+	b := d.fetchBytes(16)
+	if d.altFlag {
+		b = append([]byte(nil), b...)
+		reverse(b[0:4])
+		reverse(b[4:6])
+		reverse(b[6:8])
+	}
+	for i, n := range []int{4, 2, 2, 2, 6} {
+		if i > 0 {
+			d.buf.WriteRune('-')
+		}
+		d.buf.WriteString(hex.EncodeToString(b[:n]))
+		b = b[n:]
+	}
+}
+
+// reverse reverses the order of the bytes of b.
+func reverse(b []byte) {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+}
@@ -0,0 +1,31 @@
+package bytefmt
+
+import (
+	"testing"
+)
+
+func TestUUID(t *testing.T) {
+	buf := []byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}
+	var tests = []struct {
+		buf    []byte
+		fmt    string
+		expect string
+	}{
+		{buf, "%U", "123e4567-e89b-12d3-a456-426614174000"},
+		{buf, "%-16U", "123e4567-e89b-12d3-a456-426614174000"},
+		{buf, "%8U", "%%BADWIDTH%8"},
+		{buf, "%3U%1d", "%%BADWIDTH%3103"},
+		{buf[:8], "%U", "%%EOF%"},
+		{buf, "%#U", "67453e12-9be8-d312-a456-426614174000"},
+		{[]byte{0x33, 0x22, 0x11, 0x00, 0x55, 0x44, 0x77, 0x66, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff}, "%#U", "00112233-4455-6677-8899-aabbccddeeff"},
+		{buf[:15], "%#U", "%%EOF%"},
+		{buf, "%U", "123e4567-e89b-12d3-a456-426614174000"},
+	}
+	for _, tt := range tests {
+		res := Sprintf(tt.buf, tt.fmt)
+		if res != tt.expect {
+			t.Logf("format %q: expected %q, res %q", tt.fmt, tt.expect, res)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package bytefmt
+
+import (
+	"strconv"
+	"strings"
+)
+
+// letterVerbs lists the format letters understood by doVerb.
+const letterVerbs = "%pqCscZahBlvxowWdfEgjIMUTNKkz@|ObetirL"
+
+// A FormatError reports a malformed format or an unknown verb. A Strict
+// Dumper also reports bad values and widths in the input with it.
+type FormatError struct {
+	Pos     int    // byte position of the % starting the format
+	Verb    string // the offending format, if formatting
+	Offset  int    // the read offset in the input, if formatting
+	Problem string // what is wrong with it
+}
+
+func (e *FormatError) Error() string {
+	return "bytefmt: " + e.Problem + " at position " + strconv.Itoa(e.Pos)
+}
+
+// Validate checks that fmt only holds well formed formats with known
+// verbs, without formatting anything. It returns a *FormatError for the
+// first one that is not. Validate only knows the built-in verbs, not
+// those added to a Dumper by RegisterVerb.
+func Validate(fmt string) error {
+	var s spec
+	for i := 0; i < len(fmt); {
+		if fmt[i] != '%' {
+			i++
+			continue
+		}
+		c, name, _, next, problem := s.parse(fmt, i)
+		if problem != "" {
+			return &FormatError{Pos: i, Problem: problem}
+		}
+		if c == '(' {
+			if j := strings.IndexByte(name, ':'); j >= 0 {
+				name = name[:j]
+			}
+			if _, ok := namedVerbs[name]; !ok {
+				return &FormatError{Pos: i, Problem: "unknown verb (" + name + ")"}
+			}
+		} else if strings.IndexByte(letterVerbs, c) < 0 {
+			return &FormatError{Pos: i, Problem: "unknown verb " + string(c)}
+		}
+		i = next
+	}
+	return nil
+}
+
+// ConsumedLength returns the number of bytes formatting with fmt and the
+// arguments a consumes, without any input. Formats whose length depends
+// on the input, such as %w, a %s without a width or %(vlq), make it
+// return a *FormatError, as do malformed formats and widths flagged
+// with BadWidth, e.g. %3f.
+func ConsumedLength(fmt string, a ...interface{}) (int, error) {
+	var d Dumper
+	return d.ConsumedLength(fmt, a...)
+}
+
+// ConsumedLength is like the package level ConsumedLength, using the
+// DefaultIntWidth of d.
+func (d *Dumper) ConsumedLength(fmt string, a ...interface{}) (n int, err error) {
+	d.Reset()
+	defer func() {
+		if e := recover(); e != nil {
+			err = d.stopped(e)
+		}
+	}()
+	off := 0
+	for i := 0; i < len(fmt); {
+		if fmt[i] != '%' {
+			i++
+			continue
+		}
+		d.pos = i
+		c, name, rep, next, problem := d.spec.parse(fmt, i)
+		if problem != "" {
+			d.verb = fmt[i:]
+			d.fail(problem)
+		}
+		if d.widthArg {
+			d.argWidth(a)
+		}
+		if rep < 0 {
+			d.fail("length of " + d.verb + "... depends on the input")
+		}
+		s := d.spec
+		for r := 0; r < rep; r++ {
+			d.spec = s
+			end, ok := d.advance(c, name, off)
+			if !ok {
+				d.fail("length of " + d.verb + " depends on the input")
+			}
+			if !s.peek {
+				off = end
+			}
+		}
+		i = next
+	}
+	return (off + 7) / 8, nil
+}
+
+// advance returns the bit offset following the format with the letter
+// verb c or the named verb name at bit offset off. ok is false if that
+// depends on the input.
+func (d *Dumper) advance(c byte, name string, off int) (end int, ok bool) {
+	width := func(def int) int {
+		if !d.widthValid {
+			d.width = def
+		}
+		return (off+7)/8*8 + 8*d.width
+	}
+	// fixed is width for a verb that flags widths other than ws.
+	fixed := func(ws ...int) int {
+		end := width(ws[0])
+		for _, w := range ws {
+			if d.width == w {
+				return end
+			}
+		}
+		d.fail("bad width " + strconv.Itoa(d.width))
+		return end
+	}
+	// ints is width for an int of at most 8 bytes, by default def or
+	// DefaultIntWidth if def is zero.
+	ints := func(def int) int {
+		if def == 0 {
+			d.intWidth()
+			def = d.width
+		}
+		end := width(def)
+		if d.width > 8 {
+			d.fail("bad width " + strconv.Itoa(d.width))
+		}
+		return end
+	}
+	if _, ok := d.verbs[c]; ok && c != '(' {
+		return off, false
+	}
+	switch c {
+	case '%', 'K', 'k', 'O', 'L':
+		return off, true
+	case 'd', 'x':
+		bits, _, bad := d.intSlot(c)
+		if bits {
+			if d.prec > 64 {
+				d.fail("bad width " + strconv.Itoa(d.prec))
+			}
+			return off + d.prec, true
+		}
+		if bad {
+			d.fail("bad width " + strconv.Itoa(d.prec))
+		}
+		return ints(0), true
+	case 't':
+		// The template selected by the value consumes more.
+		return ints(0), !d.precValid
+	case 'o', 'b', 'e', 'i', 'r':
+		return ints(0), true
+	case 'v':
+		return ints(1), true
+	case 'z':
+		return width(1), true
+	case 'f', 'E', 'g':
+		return fixed(4, 2, 8, 10), true
+	case 'I':
+		return fixed(4, 16), true
+	case 'T':
+		return fixed(4, 8), true
+	case 'j':
+		return ints(4), true
+	case 'N':
+		return width(4), true
+	case 'M':
+		return fixed(6, 8), true
+	case 'U':
+		return fixed(16), true
+	case 's', 'q':
+		return width(0), d.widthValid && !d.altFlag
+	case 'p', 'C', 'a', 'l', 'h', 'B', 'Z':
+		return width(0), d.widthValid
+	case 'c', 'w', 'W':
+		return off, false
+	case '@':
+		return 8 * d.width, true
+	case '|':
+		o := (off + 7) / 8
+		if d.width > 1 {
+			o = (o + d.width - 1) / d.width * d.width
+		}
+		return 8 * o, true
+	case '(':
+		if i := strings.IndexByte(name, ':'); i >= 0 {
+			name = name[:i]
+		}
+		if _, ok := namedVerbs[name]; !ok {
+			d.fail("unknown verb (" + name + ")")
+		}
+		return d.advanceNamed(name, off, width, fixed, ints)
+	}
+	d.fail("unknown verb " + string(c))
+	return off, false
+}
+
+// advanceNamed is advance for the named verbs of a fixed length.
+func (d *Dumper) advanceNamed(name string, off int, width func(def int) int, fixed func(ws ...int) int, ints func(def int) int) (end int, ok bool) {
+	start := (off + 7) / 8 * 8
+	// count is the number of elements given by prec, if any.
+	count := 0
+	if d.precValid {
+		count = d.prec
+	}
+	switch name {
+	case "bits":
+		if !d.widthValid {
+			d.width = 1
+		}
+		if d.width > 64 {
+			d.fail("bad width " + strconv.Itoa(d.width))
+		}
+		return off + d.width, true
+	case "minifloat":
+		if !d.widthValid {
+			d.width = 5
+		}
+		if !d.precValid {
+			d.prec = 10
+		}
+		if d.width == 0 || 1+d.width+d.prec > 64 {
+			d.fail("bad width " + strconv.Itoa(d.width))
+		}
+		return off + 1 + d.width + d.prec, true
+	case "bitfloat":
+		if !d.widthValid {
+			d.width = 4
+		}
+		if d.width != 4 && d.width != 8 {
+			d.fail("bad width " + strconv.Itoa(d.width))
+		}
+		return off + 8*d.width, true
+	case "bytesum":
+		return off, true
+	case "parity":
+		return start + 8, true
+	case "bcdtime":
+		return start + 8*6, true
+	case "rgba16f":
+		return start + 8*8, true
+	case "ip6prefix":
+		return start + 8*17, true
+	case "mp4matrix":
+		return start + 8*36, true
+	case "popcount", "grid", "enumgroups":
+		return width(1), true
+	case "bcdpct":
+		return width(2), true
+	case "fourcc":
+		return width(4), true
+	case "asciitime":
+		return width(15), true
+	case "asciinums", "asciiint", "oid", "entropy":
+		return width(0), d.widthValid
+	case "palette":
+		return ints(1), true
+	case "norm", "gain", "bounded", "delta", "interp", "adc", "hexfix", "fields":
+		return ints(2), true
+	case "reserved", "signhex":
+		return ints(4), true
+	case "taitime":
+		return ints(8), true
+	case "exptime":
+		// A unit exponent byte precedes the count.
+		return ints(8) + 8, true
+	case "dosdate":
+		return fixed(2, 4), true
+	case "filetime":
+		return fixed(8), true
+	case "ntp":
+		return fixed(8, 4), true
+	case "signmag", "excess", "percent", "bar", "duration", "size":
+		return ints(0), true
+	case "fixsplit":
+		if !d.precValid {
+			d.prec = 1
+		}
+		end := ints(2)
+		if d.prec > 8 {
+			d.fail("bad width " + strconv.Itoa(d.prec))
+		}
+		return end + 8*d.prec, true
+	case "fixrow", "enums", "csv":
+		if name == "csv" && !d.precValid {
+			// The count is read from the input.
+			return off, false
+		}
+		switch {
+		case name == "fixrow":
+			ints(4)
+		case name == "enums":
+			ints(1)
+		case d.altFlag:
+			width(2)
+		default:
+			ints(2)
+		}
+		if count == 0 {
+			return off, true
+		}
+		return start + 8*d.width*count, true
+	}
+	return off, false
+}
@@ -0,0 +1,280 @@
+package bytefmt
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	var tests = []struct {
+		fmt    string
+		pos    int
+		expect string
+	}{
+		{"", 0, ""},
+		{"plain text", 0, ""},
+		{"%4d %-2x %.1e %(bits) %4.3(fixrow:16) %2d*8 %0x10s %%", 0, ""},
+		{"ok %!", 3, "unknown verb !"},
+		{"%4d %(nosuch:1)", 4, "unknown verb (nosuch)"},
+		{"%4d %", 4, "incomplete format"},
+		{"%-#", 0, "incomplete format"},
+		{"%4.", 0, "incomplete format"},
+		{"%99999999d", 0, "width or precision too large"},
+		{"%.99999999e", 0, "width or precision too large"},
+		{"%(bits", 0, "missing )"},
+	}
+	for _, tt := range tests {
+		err := Validate(tt.fmt)
+		if tt.expect == "" {
+			if err != nil {
+				t.Logf("format %q: unexpected error %v", tt.fmt, err)
+				t.Fail()
+			}
+			continue
+		}
+		e, ok := err.(*FormatError)
+		if !ok || e.Pos != tt.pos || e.Problem != tt.expect {
+			t.Logf("format %q: expected %q at %d, got %v", tt.fmt, tt.expect, tt.pos, err)
+			t.Fail()
+		}
+	}
+}
+
+func TestLetterVerbs(t *testing.T) {
+	d := NewDumper()
+	d.OnUnknownVerb = func(c byte) string { return "?" }
+	for c := 0; c < 256; c++ {
+		switch c {
+		case '#', '-', '>', '+', '_', '=', ',', '*', '{', '.', '(', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			continue
+		}
+		known := strings.IndexByte(letterVerbs, byte(c)) >= 0
+		res := d.Sprintf(make([]byte, 16), string([]byte{'%', byte(c)}), 1.0, map[int64]string{}, "")
+		if known == (res == "?") {
+			t.Logf("letter %q: listed %v, formats as %q", c, known, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestConsumedLength(t *testing.T) {
+	var tests = []struct {
+		fmt     string
+		expect  int
+		problem string
+	}{
+		{"", 0, ""},
+		{"len=%2d %4s %I %% %K%k", 10, ""},
+		{"%d %x %1v %z %8f %M %U", 4 + 4 + 1 + 1 + 8 + 6 + 16, ""},
+		{"%2d*3", 6, ""},
+		{"%=4d %4x", 4, ""},
+		{"%1d%4|%1d", 5, ""},
+		{"%.3d %.6d %1d", 3, ""},
+		{"%.3d", 1, ""},
+		{"%(bits)%3(bits)%.4d", 1, ""},
+		{"%10@%2d", 12, ""},
+		{"%2.0d %4.2d", 6, ""},
+		{"%.0d %4.2x", 4, ""},
+		{"%2.2x", 0, "bad width 2"},
+		{"%1.4d", 0, "bad width 4"},
+		{"%3I", 0, "bad width 3"},
+		{"%9x", 0, "bad width 9"},
+		{"%9e", 0, "bad width 9"},
+		{"%9v", 0, "bad width 9"},
+		{"%.65d", 0, "bad width 65"},
+		{"%9(size)", 0, "bad width 9"},
+		{"%j %9j", 0, "bad width 9"},
+		{"%1t %1.0t", 0, "length of %1.0t depends on the input"},
+		{"%3f", 0, "bad width 3"},
+		{"%2f %10E %16I %8T %8M", 2 + 10 + 16 + 8 + 8, ""},
+		{"%8U", 0, "bad width 8"},
+		{"%4(ntp) %4(dosdate)", 8, ""},
+		{"%2(filetime)", 0, "bad width 2"},
+		{"%65(bits)", 0, "bad width 65"},
+		{"%(bitfloat) %8(bitfloat)", 12, ""},
+		{"%2(bitfloat)", 0, "bad width 2"},
+		{"%0.3(minifloat)", 0, "bad width 0"},
+		{"%11.53(minifloat)", 0, "bad width 11"},
+		{"%*s %{hdr}z", 7, ""},
+		{"%2(signmag) %(filetime) %(fourcc)", 14, ""},
+		{"%w", 0, "length of %w depends on the input"},
+		{"%1d %s", 0, "length of %s depends on the input"},
+		{"%#4s", 0, "length of %#4s depends on the input"},
+		{"%2d...", 0, "length of %2d... depends on the input"},
+		{"%(kvmap)", 0, "length of %(kvmap) depends on the input"},
+		{"%(nosuch)", 0, "unknown verb (nosuch)"},
+		{"%y", 0, "unknown verb y"},
+		{"%4.", 0, "incomplete format"},
+	}
+	for _, tt := range tests {
+		n, err := ConsumedLength(tt.fmt, 3, map[string]int{"hdr": 4})
+		var problem string
+		if e, ok := err.(*FormatError); ok {
+			problem = e.Problem
+		} else if err != nil {
+			problem = err.Error()
+		}
+		if n != tt.expect || problem != tt.problem {
+			t.Logf("format %q: expected %d %q, got %d %q", tt.fmt, tt.expect, tt.problem, n, problem)
+			t.Fail()
+		}
+		if tt.problem != "" {
+			continue
+		}
+		if _, consumed := SprintfN(make([]byte, 64), tt.fmt, 3, map[string]int{"hdr": 4}); consumed != n {
+			t.Logf("format %q: length %d, but formatting consumed %d", tt.fmt, n, consumed)
+			t.Fail()
+		}
+	}
+	if _, err := ConsumedLength("%*d"); err == nil {
+		t.Logf("missing width argument: expected an error")
+		t.Fail()
+	}
+	d := NewDumper()
+	d.DefaultIntWidth = 2
+	if n, err := d.ConsumedLength("%d %x"); n != 4 || err != nil {
+		t.Logf("DefaultIntWidth: unexpected %d, %v", n, err)
+		t.Fail()
+	}
+}
+
+func TestConsumedLengthVerbs(t *testing.T) {
+	enum := map[int64]string{0: "zero"}
+	// A format for every verb, fixed if ConsumedLength can tell its
+	// length.
+	var tests = map[string]struct {
+		fmt   string
+		fixed bool
+		a     []interface{}
+	}{
+		"%":          {"%%", true, nil},
+		"p":          {"%4p", true, nil},
+		"q":          {"%4q", true, nil},
+		"C":          {"%4C", true, nil},
+		"s":          {"%4s", true, nil},
+		"c":          {"%c", false, nil},
+		"Z":          {"%4Z", true, nil},
+		"a":          {"%4a", true, nil},
+		"h":          {"%4h", true, nil},
+		"B":          {"%4B", true, nil},
+		"l":          {"%4l", true, nil},
+		"v":          {"%2v", true, nil},
+		"x":          {"%4.2x", true, nil},
+		"o":          {"%2o", true, nil},
+		"w":          {"%w", false, nil},
+		"W":          {"%W", false, nil},
+		"d":          {"%.3d", true, nil},
+		"f":          {"%8f", true, nil},
+		"E":          {"%2E", true, nil},
+		"g":          {"%10g", true, nil},
+		"j":          {"%j", true, nil},
+		"I":          {"%16I", true, nil},
+		"M":          {"%8M", true, nil},
+		"U":          {"%U", true, nil},
+		"T":          {"%8T", true, nil},
+		"N":          {"%3N", true, nil},
+		"K":          {"%K", true, nil},
+		"k":          {"%k", true, nil},
+		"z":          {"%3z", true, nil},
+		"@":          {"%5@", true, nil},
+		"|":          {"%1d%4|", true, nil},
+		"O":          {"%O", true, nil},
+		"b":          {"%2b", true, nil},
+		"e":          {"%1.0e", true, []interface{}{enum}},
+		"t":          {"%2t", true, nil},
+		"i":          {"%2.0i", true, []interface{}{1.5}},
+		"r":          {"%2.16r", true, nil},
+		"L":          {"%L", true, []interface{}{"x"}},
+		"ip6prefix":  {"%(ip6prefix)", true, nil},
+		"bcdtime":    {"%(bcdtime)", true, nil},
+		"compsize":   {"%(compsize)", false, nil},
+		"reserved":   {"%2.0(reserved)", true, []interface{}{int64(1)}},
+		"fixrow":     {"%2.3(fixrow)", true, nil},
+		"parity":     {"%3(parity)", true, nil},
+		"bits":       {"%5(bits)", true, nil},
+		"rice":       {"%.2(rice)", false, nil},
+		"present":    {"%.0(present)", false, []interface{}{"%1d"}},
+		"mp4matrix":  {"%(mp4matrix)", true, nil},
+		"kvmap":      {"%(kvmap)", false, nil},
+		"fixsplit":   {"%3.2(fixsplit)", true, nil},
+		"enums":      {"%1.3(enums)", true, []interface{}{enum}},
+		"exptime":    {"%4(exptime)", true, nil},
+		"rgba16f":    {"%(rgba16f)", true, nil},
+		"deltas":     {"%(deltas)", false, nil},
+		"gain":       {"%2(gain)", true, nil},
+		"signhex":    {"%3(signhex)", true, nil},
+		"crcblob":    {"%(crcblob)", false, nil},
+		"bounded":    {"%2.0(bounded)", true, []interface{}{0.0, 1.0}},
+		"bitfloat":   {"%8(bitfloat)", true, nil},
+		"tagged":     {"%.0(tagged)", false, []interface{}{enum}},
+		"norm":       {"%2(norm)", true, nil},
+		"dosdate":    {"%4(dosdate)", true, nil},
+		"agg":        {"%(agg)", false, nil},
+		"qauto":      {"%(qauto)", false, nil},
+		"minifloat":  {"%4.3(minifloat)", true, nil},
+		"grid":       {"%2.4(grid)", true, nil},
+		"asciinums":  {"%3(asciinums)", true, nil},
+		"bcdpct":     {"%2(bcdpct)", true, nil},
+		"utf16bom":   {"%(utf16bom)", false, nil},
+		"enumgroups": {"%2.4(enumgroups)", true, []interface{}{enum}},
+		"csv":        {"%2.3(csv)", true, nil},
+		"taitime":    {"%8(taitime)", true, nil},
+		"palette":    {"%1.0(palette)", true, []interface{}{color.Palette{color.Black}}},
+		"b64blob":    {"%(b64blob)", false, nil},
+		"delta":      {"%2(delta)", true, nil},
+		"sentinel":   {"%.0(sentinel)", false, []interface{}{int64(1)}},
+		"interp":     {"%2.0(interp)", true, []interface{}{[][2]float64{}}},
+		"adc":        {"%2.0(adc)", true, []interface{}{3.3}},
+		"mime":       {"%(mime)", false, nil},
+		"hexfix":     {"%2(hexfix)", true, nil},
+		"fields":     {"%4.0(fields)", true, []interface{}{[]BitField{{Name: "a", Bits: 8}}}},
+		"asciitime":  {"%(asciitime)", true, nil},
+		"popcount":   {"%3(popcount)", true, nil},
+		"signmag":    {"%2(signmag)", true, nil},
+		"excess":     {"%2(excess)", true, nil},
+		"lendelim":   {"%(lendelim)", false, nil},
+		"asciiint":   {"%3(asciiint)", true, nil},
+		"bytesum":    {"%(bytesum)", true, nil},
+		"fourcc":     {"%(fourcc)", true, nil},
+		"percent":    {"%2(percent)", true, nil},
+		"bar":        {"%2(bar)", true, nil},
+		"oid":        {"%3(oid)", true, nil},
+		"filetime":   {"%(filetime)", true, nil},
+		"duration":   {"%2(duration)", true, nil},
+		"vlq":        {"%(vlq)", false, nil},
+		"entropy":    {"%5(entropy)", true, nil},
+		"ntp":        {"%4(ntp)", true, nil},
+		"size":       {"%2(size)", true, nil},
+	}
+	verbs := strings.Split(letterVerbs, "")
+	for name := range namedVerbs {
+		verbs = append(verbs, name)
+	}
+	buf := make([]byte, 64)
+	for _, v := range verbs {
+		tt, ok := tests[v]
+		if !ok {
+			t.Logf("verb %q: no test", v)
+			t.Fail()
+			continue
+		}
+		n, err := ConsumedLength(tt.fmt, tt.a...)
+		if !tt.fixed {
+			if e, ok := err.(*FormatError); !ok || !strings.HasSuffix(e.Problem, "depends on the input") {
+				t.Logf("format %q: expected a length depending on the input, got %d %v", tt.fmt, n, err)
+				t.Fail()
+			}
+			continue
+		}
+		res, consumed := SprintfN(buf, tt.fmt, tt.a...)
+		if strings.Contains(res, BadArg) || strings.Contains(res, Truncated) {
+			t.Logf("format %q: unexpected %q", tt.fmt, res)
+			t.Fail()
+		}
+		if err != nil || n != consumed {
+			t.Logf("format %q: length %d %v, but formatting consumed %d", tt.fmt, n, err, consumed)
+			t.Fail()
+		}
+	}
+}